@@ -0,0 +1,115 @@
+package clean
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// A Boilerplate detector finds lines which are repeated
+// across many articles from one source, such as
+// newsletter plugs, contributor bios and print-edition
+// notices.
+//
+// Lines are compared case-insensitively, ignoring
+// punctuation, whitespace and the values of numbers, so
+// that a notice mentioning an issue date matches every
+// other issue.
+type Boilerplate struct {
+	// MinArticles is the number of articles a line must
+	// appear in to be considered boilerplate.
+	// It should be at least 2, since every line appears in
+	// the article it came from.
+	MinArticles int
+
+	counts map[string]int
+}
+
+// NewBoilerplate creates an empty detector.
+func NewBoilerplate(minArticles int) *Boilerplate {
+	return &Boilerplate{MinArticles: minArticles, counts: map[string]int{}}
+}
+
+// Add records the lines of an article.
+// Lines repeated within one article are counted once.
+func (b *Boilerplate) Add(text string) {
+	seen := map[string]bool{}
+	for _, line := range strings.Split(text, "\n") {
+		key := boilerplateKey(line)
+		if key != "" && !seen[key] {
+			seen[key] = true
+			b.counts[key]++
+		}
+	}
+}
+
+// IsBoilerplate checks if a line appeared in at least
+// MinArticles articles.
+func (b *Boilerplate) IsBoilerplate(line string) bool {
+	key := boilerplateKey(line)
+	return key != "" && b.counts[key] >= b.MinArticles
+}
+
+// Strip removes the boilerplate lines from an article,
+// returning the new text and the number of lines removed.
+//
+// Paragraphs left empty are removed entirely.
+func (b *Boilerplate) Strip(text string) (string, int) {
+	var removed int
+	var paragraphs []string
+	for _, para := range splitParagraphs(text) {
+		var kept []string
+		for _, line := range strings.Split(para, "\n") {
+			if b.IsBoilerplate(line) {
+				removed++
+			} else {
+				kept = append(kept, line)
+			}
+		}
+		if len(kept) > 0 {
+			paragraphs = append(paragraphs, strings.Join(kept, "\n"))
+		}
+	}
+	if removed == 0 {
+		return text, 0
+	}
+	return strings.Join(paragraphs, "\n\n"), removed
+}
+
+// Lines returns the normalized boilerplate lines, most
+// common first, along with the number of articles that
+// each appeared in.
+func (b *Boilerplate) Lines() ([]string, []int) {
+	var lines []string
+	for key, count := range b.counts {
+		if count >= b.MinArticles {
+			lines = append(lines, key)
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		ci, cj := b.counts[lines[i]], b.counts[lines[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		return lines[i] < lines[j]
+	})
+	counts := make([]int, len(lines))
+	for i, line := range lines {
+		counts[i] = b.counts[line]
+	}
+	return lines, counts
+}
+
+var boilerplateNumber = regexp.MustCompile(`\pN+`)
+
+// boilerplateKey normalizes a line for comparison.
+// Lines without any letters produce an empty key, so that
+// section breaks like "* * *" are never removed.
+func boilerplateKey(line string) string {
+	key := normalizeText(boilerplateNumber.ReplaceAllString(line, "0"))
+	if strings.IndexFunc(key, unicode.IsLetter) < 0 {
+		return ""
+	}
+	return key
+}
@@ -0,0 +1,44 @@
+package clean
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBoilerplate(t *testing.T) {
+	articles := []string{
+		"The first article.\n\n* * *\n\nSign up for our daily newsletter.\n\n" +
+			"This article appears in the print edition of the March 6, 2017, issue.",
+		"The second article.\nSIGN UP for our daily newsletter!",
+		"The third article.\n\n* * *\n\nThe end.\n\n" +
+			"This article appears in the print edition of the March 13, 2017, issue.",
+	}
+	b := NewBoilerplate(2)
+	for _, a := range articles {
+		b.Add(a)
+	}
+
+	lines, counts := b.Lines()
+	expectedLines := []string{
+		"sign up for our daily newsletter",
+		"this article appears in the print edition of the march 0 0 issue",
+	}
+	if !reflect.DeepEqual(lines, expectedLines) || !reflect.DeepEqual(counts, []int{2, 2}) {
+		t.Errorf("unexpected lines %v with counts %v", lines, counts)
+	}
+
+	expected := []string{
+		"The first article.\n\n* * *",
+		"The second article.",
+		"The third article.\n\n* * *\n\nThe end.",
+	}
+	for i, a := range articles {
+		res, removed := b.Strip(a)
+		if res != expected[i] {
+			t.Errorf("article %d: unexpected result %q", i, res)
+		}
+		if i == 0 && removed != 2 || i > 0 && removed != 1 {
+			t.Errorf("article %d: removed %d lines", i, removed)
+		}
+	}
+}
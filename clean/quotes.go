@@ -0,0 +1,317 @@
+// Package clean removes text which should not be used to
+// learn an author's style.
+package clean
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/unixpickle/textprint/source"
+)
+
+// LongQuoteWords is the number of words at which a quoted
+// span is considered long enough to remove.
+var LongQuoteWords = 8
+
+// A Report summarizes the text removed from an article.
+type Report struct {
+	// Original and Removed are byte counts.
+	Original int
+	Removed  int
+
+	BlockQuotes int
+	QuotedSpans int
+	Epigraphs   int
+	PullQuotes  int
+}
+
+// Fraction returns the fraction of the original text
+// which was removed.
+func (r Report) Fraction() float64 {
+	if r.Original == 0 {
+		return 0
+	}
+	return float64(r.Removed) / float64(r.Original)
+}
+
+// Add accumulates another report into r.
+func (r *Report) Add(other Report) {
+	r.Original += other.Original
+	r.Removed += other.Removed
+	r.BlockQuotes += other.BlockQuotes
+	r.QuotedSpans += other.QuotedSpans
+	r.Epigraphs += other.Epigraphs
+	r.PullQuotes += other.PullQuotes
+}
+
+// String returns a one-line summary of the report.
+func (r Report) String() string {
+	return fmt.Sprintf("removed %d of %d bytes (%.1f%%): %d block quotes, "+
+		"%d quoted spans, %d epigraphs, %d pull quotes", r.Removed, r.Original,
+		100*r.Fraction(), r.BlockQuotes, r.QuotedSpans, r.Epigraphs, r.PullQuotes)
+}
+
+var (
+	curlyQuoteSpan    = regexp.MustCompile(`“[^“”]*”`)
+	straightQuoteSpan = regexp.MustCompile(`"[^"\n]*"`)
+	quoteAttribution  = regexp.MustCompile(`^(—|–|―|--)\s*\S`)
+	quoteSentenceEnd  = regexp.MustCompile(`[.!?]["”’)]*(\s|$)`)
+)
+
+// Quotes removes quoted material from a plain-text
+// article, whose paragraphs are separated by blank lines.
+//
+// Paragraphs which are entirely quoted (including ones
+// which open a quotation that continues into the next
+// paragraph, and lines starting with ">") are treated as
+// block quotes.
+// A short opening quote followed by an attribution line,
+// like "— Author", is treated as an epigraph.
+// Paragraphs repeating text from elsewhere in the article
+// are treated as pull quotes.
+// Finally, sentences containing a quoted span of at least
+// LongQuoteWords words are removed.
+func Quotes(text string) (string, Report) {
+	report := Report{Original: len(text)}
+	paragraphs := splitParagraphs(text)
+	paragraphs = removeEpigraph(paragraphs, &report)
+	paragraphs = removePullQuotes(paragraphs, &report)
+
+	var kept []string
+	for _, para := range paragraphs {
+		if isBlockQuote(para) {
+			report.BlockQuotes++
+			continue
+		}
+		if para = removeQuotedSpans(para, &report); para != "" {
+			kept = append(kept, para)
+		}
+	}
+
+	res := strings.Join(kept, "\n\n")
+	report.Removed = len(text) - len(res)
+	return res, report
+}
+
+// QuotesDocument renders a document as plain text without
+// its quotes.
+//
+// Quote blocks are dropped, and are counted as epigraphs
+// if they come before any prose or as pull quotes if they
+// repeat text from another block.
+// The remaining text is then cleaned with Quotes.
+func QuotesDocument(doc *source.Document) (string, Report) {
+	original := doc.Text()
+
+	var report Report
+	var kept []string
+	seenProse := false
+	for i, block := range doc.Blocks {
+		if block.Type != source.Quote {
+			if block.Type == source.Prose {
+				seenProse = true
+			}
+			kept = append(kept, block.Text)
+			continue
+		}
+		if !seenProse {
+			report.Epigraphs++
+		} else if repeatsOtherBlock(doc, i) {
+			report.PullQuotes++
+		} else {
+			report.BlockQuotes++
+		}
+	}
+
+	res, textReport := Quotes(strings.Join(kept, "\n\n"))
+	report.Add(textReport)
+	report.Original = len(original)
+	report.Removed = len(original) - len(res)
+	return res, report
+}
+
+func repeatsOtherBlock(doc *source.Document, idx int) bool {
+	quote := normalizeText(doc.Blocks[idx].Text)
+	if len(strings.Fields(quote)) < 4 {
+		return false
+	}
+	for i, block := range doc.Blocks {
+		if i != idx && block.Type != source.Quote &&
+			strings.Contains(normalizeText(block.Text), quote) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitParagraphs(text string) []string {
+	var res []string
+	for _, para := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n\n") {
+		if para = strings.TrimSpace(para); para != "" {
+			res = append(res, para)
+		}
+	}
+	return res
+}
+
+// removeEpigraph removes a quotation and its attribution
+// from the start of an article.
+func removeEpigraph(paragraphs []string, report *Report) []string {
+	if len(paragraphs) < 2 {
+		return paragraphs
+	}
+
+	// The attribution may be the last line of the first
+	// paragraph or a paragraph of its own.
+	lines := strings.Split(paragraphs[0], "\n")
+	if len(lines) > 1 && isAttribution(lines[len(lines)-1]) &&
+		len(strings.Fields(paragraphs[0])) <= 80 {
+		report.Epigraphs++
+		return paragraphs[1:]
+	}
+	if len(paragraphs) > 2 && isAttribution(paragraphs[1]) &&
+		len(strings.Fields(paragraphs[0])) <= 80 {
+		report.Epigraphs++
+		return paragraphs[2:]
+	}
+	return paragraphs
+}
+
+func isAttribution(line string) bool {
+	line = strings.TrimSpace(line)
+	return quoteAttribution.MatchString(line) && len(strings.Fields(line)) <= 12
+}
+
+// removePullQuotes removes paragraphs whose text is
+// repeated inside another paragraph.
+func removePullQuotes(paragraphs []string, report *Report) []string {
+	normalized := make([]string, len(paragraphs))
+	for i, para := range paragraphs {
+		normalized[i] = normalizeText(strings.Trim(para, `"“”`))
+	}
+
+	var res []string
+	for i, para := range paragraphs {
+		pull := false
+		if len(strings.Fields(normalized[i])) >= 4 {
+			for j, other := range normalized {
+				if j != i && len(other) > len(normalized[i]) &&
+					strings.Contains(other, normalized[i]) {
+					pull = true
+					break
+				}
+			}
+		}
+		if pull {
+			report.PullQuotes++
+		} else {
+			res = append(res, para)
+		}
+	}
+	return res
+}
+
+// normalizeText lowercases text and reduces it to words,
+// so that differences in punctuation and whitespace are
+// ignored.
+func normalizeText(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	return strings.Join(words, " ")
+}
+
+func isBlockQuote(para string) bool {
+	lines := strings.Split(para, "\n")
+	quoted := true
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), ">") {
+			quoted = false
+			break
+		}
+	}
+	if quoted {
+		return true
+	}
+
+	if !strings.HasPrefix(para, `"`) && !strings.HasPrefix(para, "“") {
+		return false
+	}
+	spans := quoteSpans(para)
+	if len(spans) == 0 || spans[0][0] != 0 {
+		// The quotation continues into the next paragraph.
+		return len(strings.Fields(para)) >= LongQuoteWords
+	}
+	span := spans[0]
+	rest := strings.TrimSpace(para[span[1]:])
+	return rest == "" && len(strings.Fields(para)) >= LongQuoteWords
+}
+
+// removeQuotedSpans removes every sentence containing a
+// long quoted span.
+func removeQuotedSpans(para string, report *Report) string {
+	for {
+		var start, end int
+		found := false
+		for _, span := range quoteSpans(para) {
+			if len(strings.Fields(para[span[0]:span[1]])) >= LongQuoteWords {
+				start, end = sentenceBounds(para, span[0], span[1])
+				found = true
+				break
+			}
+		}
+		if !found {
+			return para
+		}
+		report.QuotedSpans++
+		before := strings.TrimSpace(para[:start])
+		after := strings.TrimSpace(para[end:])
+		if before != "" && after != "" {
+			para = before + " " + after
+		} else {
+			para = before + after
+		}
+	}
+}
+
+// quoteSpans finds the quoted spans in a paragraph.
+//
+// Straight quotes cannot be told apart, so they are only
+// paired up when they balance within the paragraph.
+// Otherwise, a stray quote would make the text between
+// two quotations look like a quotation.
+func quoteSpans(para string) [][]int {
+	spans := curlyQuoteSpan.FindAllStringIndex(para, -1)
+	if strings.Count(para, `"`)%2 == 0 {
+		spans = append(spans, straightQuoteSpan.FindAllStringIndex(para, -1)...)
+		sort.Slice(spans, func(i, j int) bool {
+			return spans[i][0] < spans[j][0]
+		})
+	}
+	return spans
+}
+
+// sentenceBounds finds the start and end of the sentences
+// around a quoted span.
+func sentenceBounds(para string, spanStart, spanEnd int) (start, end int) {
+	for _, match := range quoteSentenceEnd.FindAllStringIndex(para[:spanStart], -1) {
+		start = match[1]
+	}
+
+	quoted := strings.TrimRight(para[spanStart:spanEnd], `"”`)
+	rest := para[spanEnd:]
+	if strings.HasSuffix(quoted, ".") || strings.HasSuffix(quoted, "!") ||
+		strings.HasSuffix(quoted, "?") {
+		trimmed := strings.TrimLeft(rest, " \t\n")
+		if trimmed == "" || !unicode.IsLower([]rune(trimmed)[0]) {
+			return start, spanEnd
+		}
+	}
+	if match := quoteSentenceEnd.FindStringIndex(rest); match != nil {
+		return start, spanEnd + match[1]
+	}
+	return start, len(para)
+}
@@ -0,0 +1,92 @@
+package clean
+
+import (
+	"testing"
+
+	"github.com/unixpickle/textprint/source"
+)
+
+func TestQuotes(t *testing.T) {
+	text := "Not all those who wander are lost.\n— J. R. R. Tolkien\n\n" +
+		"I went to the market on Tuesday. The vendor told me, “We have not seen " +
+		"prices like these since before the war started.” I bought apples anyway. " +
+		"He said \"fine\" and left.\n\n" +
+		"“This is a long block quote which somebody else wrote, and it runs on " +
+		"for a while.”\n\n" +
+		"> An emailed reply\n> spanning two lines\n\n" +
+		"prices like these since before\n\n" +
+		"The end."
+	expected := "I went to the market on Tuesday. I bought apples anyway. " +
+		"He said \"fine\" and left.\n\nThe end."
+
+	res, report := Quotes(text)
+	if res != expected {
+		t.Errorf("unexpected result: %q", res)
+	}
+	if report.Epigraphs != 1 || report.QuotedSpans != 1 || report.BlockQuotes != 2 ||
+		report.PullQuotes != 1 {
+		t.Errorf("unexpected report: %v", report)
+	}
+	if report.Original != len(text) || report.Removed != len(text)-len(expected) {
+		t.Errorf("unexpected byte counts: %v", report)
+	}
+}
+
+func TestQuotesAttribution(t *testing.T) {
+	text := "“I never expected to be standing here in front of all of you,” " +
+		"she said, smiling. Then the lights went out."
+	res, report := Quotes(text)
+	if res != "Then the lights went out." {
+		t.Errorf("unexpected result: %q", res)
+	}
+	if report.QuotedSpans != 1 {
+		t.Errorf("unexpected report: %v", report)
+	}
+}
+
+func TestQuotesUnbalanced(t *testing.T) {
+	// The stray quote after 12 must not be paired with the
+	// quote opening "fine", or the sentences between them
+	// would be removed.
+	text := "The board was 12\" wide. I measured it twice before cutting, " +
+		"because the wood was expensive and hard to find. He said \"fine\" and left."
+	res, report := Quotes(text)
+	if res != text {
+		t.Errorf("unexpected result: %q", res)
+	}
+	if report.QuotedSpans != 0 {
+		t.Errorf("unexpected report: %v", report)
+	}
+
+	text = "I measured twice. He said \"this board is far too short to span " +
+		"the gap\" and left. The board was 12\" wide."
+	res, _ = Quotes(text)
+	if res != text {
+		t.Errorf("unexpected result: %q", res)
+	}
+}
+
+func TestQuotesDocument(t *testing.T) {
+	doc := &source.Document{
+		Blocks: []source.Block{
+			{Type: source.Quote, Text: "An epigraph."},
+			{Type: source.Heading, Text: "Title"},
+			{Type: source.Prose, Text: "The first paragraph has a memorable line in it."},
+			{Type: source.Quote, Text: "a memorable line in it"},
+			{Type: source.Quote, Text: "Somebody else's words."},
+			{Type: source.Prose, Text: "The last paragraph."},
+		},
+	}
+	res, report := QuotesDocument(doc)
+	expected := "Title\n\nThe first paragraph has a memorable line in it.\n\n" +
+		"The last paragraph."
+	if res != expected {
+		t.Errorf("unexpected result: %q", res)
+	}
+	if report.Epigraphs != 1 || report.PullQuotes != 1 || report.BlockQuotes != 1 {
+		t.Errorf("unexpected report: %v", report)
+	}
+	if report.Removed != len(doc.Text())-len(expected) {
+		t.Errorf("unexpected byte counts: %v", report)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/unixpickle/textprint/source"
+)
+
+// allowAuthor checks an author's name against the author
+// filters in opts.
+// Names in the allow and deny lists are matched without
+// regard to case.
+func allowAuthor(opts fetchOptions, name string) bool {
+	if opts.AuthorPattern.Regexp != nil && !opts.AuthorPattern.MatchString(name) {
+		return false
+	}
+	if len(opts.Allow) > 0 && !containsFold(opts.Allow, name) {
+		return false
+	}
+	return !containsFold(opts.Deny, name)
+}
+
+// allowDate checks an article's date against the date
+// range in opts.
+// The date is only read when there is a range, since some
+// sources fetch the article to find it.
+// Undated articles are excluded when there is a range.
+func allowDate(opts fetchOptions, art source.Article) bool {
+	if opts.After.IsZero() && opts.Before.IsZero() {
+		return true
+	}
+	date, _ := art.Date()
+	if date.IsZero() || date.Before(opts.After.Time) {
+		return false
+	}
+	return opts.Before.IsZero() || date.Before(opts.Before.Time)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, x := range list {
+		if strings.EqualFold(x, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// countArticles counts the articles saved in an author's
+// directory.
+func countArticles(authorPath string) int {
+	var res int
+	listing, _ := ioutil.ReadDir(authorPath)
+	for _, info := range listing {
+		if filepath.Ext(info.Name()) == ".txt" {
+			res++
+		}
+	}
+	return res
+}
+
+// listFlag is a flag.Value for a comma-separated list.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(s string) error {
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// regexpFlag is a flag.Value for a regular expression.
+type regexpFlag struct {
+	*regexp.Regexp
+}
+
+func (r *regexpFlag) String() string {
+	if r.Regexp == nil {
+		return ""
+	}
+	return r.Regexp.String()
+}
+
+func (r *regexpFlag) Set(s string) error {
+	var err error
+	r.Regexp, err = regexp.Compile(s)
+	return err
+}
+
+// dateFlag is a flag.Value for a date like 2006-01-02.
+type dateFlag struct {
+	time.Time
+}
+
+func (d *dateFlag) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format("2006-01-02")
+}
+
+func (d *dateFlag) Set(s string) error {
+	var err error
+	d.Time, err = time.Parse("2006-01-02", s)
+	return err
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/textprint/source"
+)
+
+func TestAllowAuthor(t *testing.T) {
+	tests := []struct {
+		opts     fetchOptions
+		name     string
+		expected bool
+	}{
+		{fetchOptions{}, "Alice", true},
+		{fetchOptions{AuthorPattern: regexpFlag{regexp.MustCompile("^A")}}, "Alice", true},
+		{fetchOptions{AuthorPattern: regexpFlag{regexp.MustCompile("^A")}}, "Bob", false},
+		{fetchOptions{Allow: listFlag{"alice", "Carol"}}, "Alice", true},
+		{fetchOptions{Allow: listFlag{"alice", "Carol"}}, "Bob", false},
+		{fetchOptions{Deny: listFlag{"BOB"}}, "Bob", false},
+		{fetchOptions{Deny: listFlag{"BOB"}}, "Alice", true},
+		{fetchOptions{Allow: listFlag{"Bob"}, Deny: listFlag{"Bob"}}, "Bob", false},
+	}
+	for i, test := range tests {
+		if actual := allowAuthor(test.opts, test.name); actual != test.expected {
+			t.Errorf("test %d: expected %v but got %v", i, test.expected, actual)
+		}
+	}
+}
+
+func TestAllowDate(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2017, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		after    time.Time
+		before   time.Time
+		date     time.Time
+		expected bool
+	}{
+		{time.Time{}, time.Time{}, time.Time{}, true},
+		{day(2), time.Time{}, day(2), true},
+		{day(2), time.Time{}, day(1), false},
+		{time.Time{}, day(2), day(1), true},
+		{time.Time{}, day(2), day(2), false},
+		{day(1), day(3), time.Time{}, false},
+	}
+	for i, test := range tests {
+		opts := fetchOptions{After: dateFlag{test.after}, Before: dateFlag{test.before}}
+		art := &fakeArticle{date: test.date}
+		if actual := allowDate(opts, art); actual != test.expected {
+			t.Errorf("test %d: expected %v but got %v", i, test.expected, actual)
+		}
+		if i == 0 && art.dates != 0 {
+			t.Error("date read without a date range")
+		}
+	}
+}
+
+func TestFetchFilters(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2017, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	newSource := func() *fakeSource {
+		return &fakeSource{authors: []source.Author{
+			&fakeAuthor{name: "Alice", articles: []*fakeArticle{
+				{id: "a3", body: "Alice three.", date: day(3)},
+				{id: "a2", body: "Alice two.", date: day(2)},
+				{id: "a1", body: "Alice one, the longest.", date: day(1)},
+			}},
+			&fakeAuthor{name: "Bob", articles: []*fakeArticle{
+				{id: "b1", body: "Bob one.", date: day(1)},
+			}},
+			&fakeAuthor{name: "Carol", articles: []*fakeArticle{
+				{id: "c2", body: "Carol two.", date: day(2)},
+				{id: "c1", body: "Carol one.", date: day(1)},
+			}},
+		}}
+	}
+
+	tests := []struct {
+		opts     fetchOptions
+		expected []string
+	}{
+		{
+			fetchOptions{MaxArticles: -1},
+			[]string{"Alice/a1.txt", "Alice/a2.txt", "Alice/a3.txt", "Bob/b1.txt",
+				"Carol/c1.txt", "Carol/c2.txt"},
+		},
+		{
+			fetchOptions{MaxArticles: 1},
+			[]string{"Alice/a3.txt", "Bob/b1.txt", "Carol/c2.txt"},
+		},
+		{
+			fetchOptions{MaxArticles: -1, MinArticles: 2},
+			[]string{"Alice/a1.txt", "Alice/a2.txt", "Alice/a3.txt", "Carol/c1.txt",
+				"Carol/c2.txt"},
+		},
+		{
+			fetchOptions{MaxArticles: -1, MaxAuthors: 2, Deny: listFlag{"alice"}},
+			[]string{"Bob/b1.txt", "Carol/c1.txt", "Carol/c2.txt"},
+		},
+		{
+			fetchOptions{MaxArticles: -1, AuthorPattern: regexpFlag{regexp.MustCompile("o")}},
+			[]string{"Bob/b1.txt", "Carol/c1.txt", "Carol/c2.txt"},
+		},
+		{
+			fetchOptions{MaxArticles: -1, After: dateFlag{day(2)}, Before: dateFlag{day(3)}},
+			[]string{"Alice/a2.txt", "Carol/c2.txt"},
+		},
+		{
+			fetchOptions{MaxArticles: -1, MinLength: 15},
+			[]string{"Alice/a1.txt"},
+		},
+	}
+	for i, test := range tests {
+		out := t.TempDir()
+		test.opts.MaxAttempts = 3
+		fetchIntoDir(newSource(), out, test.opts)
+		if files := outputFiles(t, out); !reflect.DeepEqual(files, test.expected) {
+			t.Errorf("test %d: expected %v but got %v", i, test.expected, files)
+		}
+	}
+}
+
+func TestFetchDateRangeResume(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2017, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	fetched := &fakeArticle{id: "a2", body: "Two.", date: day(2)}
+	s := &fakeSource{authors: []source.Author{&fakeAuthor{name: "Alice", articles: []*fakeArticle{
+		{id: "a3", err: errors.New("timeout"), date: day(3)},
+		fetched,
+		{id: "a1", body: "One.", date: day(1)},
+	}}}}
+	out := t.TempDir()
+	opts := fetchOptions{MaxArticles: -1, MaxAttempts: 3, After: dateFlag{day(2)}}
+	fetchIntoDir(s, out, opts)
+
+	// The failed article brings the author back, but the
+	// fetched article must not be dated again.
+	dates, fetches := fetched.dates, fetched.fetches
+	fetchIntoDir(s, out, opts)
+	if fetched.dates != dates || fetched.fetches != fetches {
+		t.Error("fetched article was read again")
+	}
+	if files := outputFiles(t, out); !reflect.DeepEqual(files, []string{"Alice/a2.txt"}) {
+		t.Errorf("unexpected files %v", files)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/unixpickle/textprint/source"
+)
+
+// authorListing is the JSON output of list-authors.
+type authorListing struct {
+	Name string `json:"name"`
+}
+
+// articleListing is the JSON output of list-articles.
+type articleListing struct {
+	ID   string `json:"id"`
+	URL  string `json:"url,omitempty"`
+	Date string `json:"date,omitempty"`
+}
+
+// listAuthors writes the authors from a source as JSON
+// lines, or writes the number of authors if count is set.
+func listAuthors(w io.Writer, s source.Source, count bool) error {
+	authors, errChan := s.Authors(nil)
+	enc := json.NewEncoder(w)
+	var n int
+	for author := range authors {
+		n++
+		if !count {
+			if err := enc.Encode(authorListing{Name: author.Name()}); err != nil {
+				return err
+			}
+		}
+	}
+	if err := <-errChan; err != nil {
+		return err
+	}
+	if count {
+		fmt.Fprintln(w, n)
+	}
+	return nil
+}
+
+// listArticles writes the articles of one author as JSON
+// lines, or writes the number of articles if count is set.
+//
+// The author name is matched without regard to case.
+// Articles are never fetched, unless dates is set, in
+// which case sources which date articles from their pages
+// fetch each page to read its date.
+// Filters which a source applies when an article is
+// fetched (such as the NewYorker types) are not applied,
+// so the results may include articles which fetch skips.
+func listArticles(w io.Writer, s source.Source, name string, count, dates bool) error {
+	stop := make(chan struct{})
+	authors, errChan := s.Authors(stop)
+	var author source.Author
+	for a := range authors {
+		if strings.EqualFold(a.Name(), name) {
+			author = a
+			break
+		}
+	}
+	close(stop)
+	if err := <-errChan; err != nil {
+		return err
+	}
+	if author == nil {
+		return errors.New("unknown author: " + name)
+	}
+
+	arts, errChan := author.Articles(nil)
+	enc := json.NewEncoder(w)
+	var n int
+	for art := range arts {
+		n++
+		if count {
+			continue
+		}
+		listing := articleListing{ID: art.ID()}
+		if located, ok := art.(source.LocatedArticle); ok {
+			listing.URL = located.URL()
+		}
+		if dates {
+			if date, err := art.Date(); err == nil && !date.IsZero() {
+				listing.Date = date.Format(time.RFC3339)
+			}
+		}
+		if err := enc.Encode(listing); err != nil {
+			return err
+		}
+	}
+	if err := <-errChan; err != nil {
+		return err
+	}
+	if count {
+		fmt.Fprintln(w, n)
+	}
+	return nil
+}
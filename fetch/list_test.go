@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/textprint/source"
+)
+
+func TestList(t *testing.T) {
+	arts := []*fakeArticle{
+		{id: "a1", date: time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{id: "a2"},
+	}
+	s := &fakeSource{authors: []source.Author{
+		&fakeAuthor{name: "Alice", articles: arts},
+		&fakeAuthor{name: "Bob"},
+	}}
+
+	tests := []struct {
+		list     func(buf *bytes.Buffer) error
+		expected string
+	}{
+		{
+			func(buf *bytes.Buffer) error { return listAuthors(buf, s, false) },
+			`{"name":"Alice"}` + "\n" + `{"name":"Bob"}` + "\n",
+		},
+		{
+			func(buf *bytes.Buffer) error { return listAuthors(buf, s, true) },
+			"2\n",
+		},
+		{
+			func(buf *bytes.Buffer) error { return listArticles(buf, s, "alice", false, false) },
+			`{"id":"a1","url":"http://example.com/a1"}` + "\n" +
+				`{"id":"a2","url":"http://example.com/a2"}` + "\n",
+		},
+		{
+			func(buf *bytes.Buffer) error { return listArticles(buf, s, "alice", false, true) },
+			`{"id":"a1","url":"http://example.com/a1","date":"2017-01-02T00:00:00Z"}` + "\n" +
+				`{"id":"a2","url":"http://example.com/a2"}` + "\n",
+		},
+		{
+			func(buf *bytes.Buffer) error { return listArticles(buf, s, "Alice", true, false) },
+			"2\n",
+		},
+		{
+			func(buf *bytes.Buffer) error { return listArticles(buf, s, "Bob", true, false) },
+			"0\n",
+		},
+	}
+	for i, test := range tests {
+		var buf bytes.Buffer
+		if err := test.list(&buf); err != nil {
+			t.Errorf("test %d: %s", i, err)
+		} else if buf.String() != test.expected {
+			t.Errorf("test %d: unexpected output %q", i, buf.String())
+		}
+	}
+
+	for _, art := range arts {
+		if art.fetches != 0 || art.dates != 1 {
+			t.Errorf("article %s: fetched %d times and dated %d times", art.id,
+				art.fetches, art.dates)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := listArticles(&buf, s, "Carol", false, false); err == nil {
+		t.Error("expected error for unknown author")
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -8,41 +9,217 @@ import (
 	"path/filepath"
 	"sort"
 	"strconv"
+	"time"
+	"unicode/utf8"
 
+	"github.com/unixpickle/textprint/clean"
 	"github.com/unixpickle/textprint/source"
 )
 
+// fetchOptions controls which articles fetchIntoDir
+// saves.
+type fetchOptions struct {
+	// MaxArticles limits the number of articles saved for
+	// each author, including those saved by earlier runs,
+	// or is -1 for no limit.
+	MaxArticles int
+
+	// MinArticles is the number of articles an author needs
+	// to be kept.
+	// Authors with fewer articles once their listing is
+	// exhausted have their directories removed, and are
+	// skipped by later runs unless MinArticles is lowered
+	// enough to keep them.
+	MinArticles int
+
+	// MaxAuthors limits the number of authors with saved
+	// articles, or is 0 for no limit.
+	MaxAuthors int
+
+	// AuthorPattern, Allow and Deny select authors by name.
+	AuthorPattern regexpFlag
+	Allow         listFlag
+	Deny          listFlag
+
+	// After and Before limit the dates of articles, if
+	// they are non-zero.
+	After  dateFlag
+	Before dateFlag
+
+	// MinLength is the minimum number of characters in an
+	// article's body.
+	MinLength int
+
+	// Coauthored keeps articles with more than one author.
+	Coauthored bool
+
+	// NoQuotes removes quoted material from articles,
+	// recording what was removed in a report file.
+	NoQuotes bool
+
+	// MaxAttempts is the number of times to try fetching
+	// an article before giving up on it.
+	MaxAttempts int
+
+	// Incremental revisits finished authors, but stops
+	// listing their articles upon reaching one which was
+	// already fetched and is no newer than the newest local
+	// article.
+	// Unfinished authors are resumed as usual.
+	// Newly fetched articles are listed in addedFile.
+	Incremental bool
+
+	// Cookies is the path of a Netscape-format cookie
+	// file to use for requests, or "" to use no cookies.
+	Cookies string
+
+	// Boilerplate is the number of articles a line must
+	// appear in to be stripped after fetching, or 0 to keep
+	// every line.
+	Boilerplate int
+}
+
+// addedFile is the file in the output directory which
+// lists the articles added by incremental updates.
+const addedFile = "added.tsv"
+
+// quoteReportFile is the file in the output directory
+// which lists the quotes removed from each article.
+const quoteReportFile = "quote_report.tsv"
+
 func main() {
-	if len(os.Args) == 3 {
-		if os.Args[1] != "help" {
-			dieUsage()
-		}
+	if len(os.Args) == 3 && os.Args[1] == "help" {
 		dieHelp(os.Args[2])
-	} else if len(os.Args) == 4 || len(os.Args) == 5 {
-		if os.Args[1] != "fetch" {
+	} else if len(os.Args) >= 4 && os.Args[1] == "fetch" {
+		opts := fetchOptions{MaxArticles: -1, MaxAttempts: 3}
+		flags := fetchFlags(&opts)
+		flags.Parse(os.Args[2:])
+
+		args := flags.Args()
+		if len(args) != 2 && len(args) != 3 {
 			dieUsage()
 		}
-		s, ok := source.Sources[os.Args[2]]
+		s, ok := source.Sources[args[0]]
 		if !ok {
-			fmt.Fprintln(os.Stderr, "Unknown source:", os.Args[2])
+			fmt.Fprintln(os.Stderr, "Unknown source:", args[0])
 			os.Exit(1)
 		}
-		maxArt := -1
-		if len(os.Args) == 5 {
+		if opts.Boilerplate == 1 || opts.Boilerplate < 0 {
+			fmt.Fprintln(os.Stderr, "-boilerplate must be 0 or at least 2")
+			dieUsage()
+		}
+		if len(args) == 3 {
 			var err error
-			maxArt, err = strconv.Atoi(os.Args[4])
+			opts.MaxArticles, err = strconv.Atoi(args[2])
 			if err != nil {
-				fmt.Fprintln(os.Stderr, "Invalid max_art:", os.Args[4])
+				fmt.Fprintln(os.Stderr, "Invalid max_art:", args[2])
+				os.Exit(1)
+			}
+		}
+		if opts.Cookies != "" {
+			if err := source.UseCookieFile(opts.Cookies); err != nil {
+				fmt.Fprintln(os.Stderr, "Failed to load cookies:", err)
+				os.Exit(1)
+			}
+		}
+		fetchIntoDir(s, args[1], opts)
+		if opts.Boilerplate > 0 {
+			if err := stripBoilerplate(args[1], opts.Boilerplate); err != nil {
+				fmt.Fprintln(os.Stderr, "Failed to strip boilerplate:", err)
 				os.Exit(1)
 			}
 		}
-		fetchIntoDir(s, os.Args[3], maxArt)
+	} else if len(os.Args) >= 3 && (os.Args[1] == "list-authors" || os.Args[1] == "list-articles") {
+		var count, dates bool
+		flags := listFlags(os.Args[1], &count, &dates)
+		flags.Parse(os.Args[2:])
+
+		args := flags.Args()
+		if os.Args[1] == "list-authors" && len(args) != 1 ||
+			os.Args[1] == "list-articles" && len(args) != 2 {
+			dieUsage()
+		}
+		s, ok := source.Sources[args[0]]
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Unknown source:", args[0])
+			os.Exit(1)
+		}
+		var err error
+		if os.Args[1] == "list-authors" {
+			err = listAuthors(os.Stdout, s, count)
+		} else {
+			err = listArticles(os.Stdout, s, args[1], count, dates)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to list:", err)
+			os.Exit(1)
+		}
+	} else if len(os.Args) >= 3 && os.Args[1] == "strip" {
+		flags := flag.NewFlagSet("strip", flag.ExitOnError)
+		flags.SetOutput(os.Stderr)
+		flags.Usage = dieUsage
+		minArticles := flags.Int("min", 5, "number of articles a line must appear in")
+		flags.Parse(os.Args[2:])
+		if flags.NArg() != 1 {
+			dieUsage()
+		}
+		if *minArticles < 2 {
+			fmt.Fprintln(os.Stderr, "-min must be at least 2")
+			dieUsage()
+		}
+		if err := stripBoilerplate(flags.Arg(0), *minArticles); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to strip boilerplate:", err)
+			os.Exit(1)
+		}
 	} else {
 		dieUsage()
 	}
 }
 
-func fetchIntoDir(s source.Source, out string, maxArt int) {
+// fetchFlags creates the flags for the fetch command,
+// which are stored in opts.
+func fetchFlags(opts *fetchOptions) *flag.FlagSet {
+	flags := flag.NewFlagSet("fetch", flag.ExitOnError)
+	flags.SetOutput(os.Stderr)
+	flags.Usage = dieUsage
+	flags.IntVar(&opts.MaxArticles, "max-articles", opts.MaxArticles,
+		"maximum articles per author, including earlier runs (-1 for no limit; same as max_art)")
+	flags.IntVar(&opts.MinArticles, "min-articles", 0,
+		"remove authors with fewer articles than this")
+	flags.IntVar(&opts.MaxAuthors, "max-authors", 0, "maximum number of authors (0 for no limit)")
+	flags.Var(&opts.AuthorPattern, "author-regexp", "only fetch authors whose names match this regexp")
+	flags.Var(&opts.Allow, "allow", "comma-separated author names to fetch")
+	flags.Var(&opts.Deny, "deny", "comma-separated author names to skip")
+	flags.Var(&opts.After, "after", "only fetch articles dated on or after this day (2006-01-02)")
+	flags.Var(&opts.Before, "before", "only fetch articles dated before this day (2006-01-02)")
+	flags.IntVar(&opts.MinLength, "min-length", 0, "minimum number of characters in an article")
+	flags.BoolVar(&opts.Coauthored, "coauthored", false, "keep articles with more than one author")
+	flags.BoolVar(&opts.NoQuotes, "noquotes", false, "remove block quotes, epigraphs and long quotations")
+	flags.IntVar(&opts.MaxAttempts, "attempts", opts.MaxAttempts,
+		"number of times to try fetching an article across resumed runs")
+	flags.BoolVar(&opts.Incremental, "incremental", false,
+		"for finished authors, only fetch articles newer than the ones already fetched")
+	flags.StringVar(&opts.Cookies, "cookies", "",
+		"Netscape-format cookie file for logged-in requests (updated with new cookies)")
+	flags.IntVar(&opts.Boilerplate, "boilerplate", 0,
+		"after fetching, strip lines repeated in at least this many articles")
+	return flags
+}
+
+// listFlags creates the flags for the list-authors and
+// list-articles commands.
+func listFlags(name string, count, dates *bool) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.SetOutput(os.Stderr)
+	flags.Usage = dieUsage
+	flags.BoolVar(count, "count", false, "print the number of results instead of listing them "+
+		"(filters which sources apply when fetching are not applied)")
+	flags.BoolVar(dates, "dates", false,
+		"list the date of each article, which may download its page")
+	return flags
+}
+
+func fetchIntoDir(s source.Source, out string, opts fetchOptions) {
 	if _, err := os.Stat(out); os.IsNotExist(err) {
 		if err := os.Mkdir(out, 0755); err != nil {
 			fmt.Fprintln(os.Stderr, "Failed to create output directory:", err)
@@ -50,11 +227,96 @@ func fetchIntoDir(s source.Source, out string, maxArt int) {
 		}
 	}
 
-	authors, errChan := s.Authors(nil)
+	var report *os.File
+	if opts.NoQuotes {
+		var err error
+		report, err = os.OpenFile(filepath.Join(out, quoteReportFile),
+			os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to open quote report:", err)
+			os.Exit(1)
+		}
+		defer report.Close()
+	}
+
+	var added *os.File
+	if opts.Incremental {
+		var err error
+		added, err = os.OpenFile(filepath.Join(out, addedFile),
+			os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to open list of added articles:", err)
+			os.Exit(1)
+		}
+		defer added.Close()
+	}
+	runTime := time.Now().Format(time.RFC3339)
+
+	state, err := openCrawlState(out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to open crawl state:", err)
+		os.Exit(1)
+	}
+	defer state.Close()
+	record := func(event crawlEvent) {
+		if err := state.Record(event); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to save crawl state:", err)
+			os.Exit(1)
+		}
+	}
+
+	authorStop := make(chan struct{})
+	authors, errChan, restored := crawlAuthors(s, state, opts, authorStop)
+	if restored {
+		log.Println("Using the authors listed by a previous run.")
+	}
+	var numAuthors int
+	stopped := false
+	allKeyed := true
 	for author := range authors {
+		if opts.MaxAuthors > 0 && numAuthors >= opts.MaxAuthors {
+			log.Println("Reached maximum number of authors.")
+			close(authorStop)
+			stopped = true
+			break
+		}
+		if keyed, ok := author.(source.KeyedAuthor); !ok {
+			allKeyed = false
+		} else if _, ok := state.keys[author.Name()]; !ok {
+			record(crawlEvent{Author: author.Name(), Key: keyed.Key()})
+		}
+		if !allowAuthor(opts, author.Name()) {
+			log.Println("Skipping filtered author:", author.Name())
+			continue
+		}
+		authorPath := filepath.Join(out, author.Name())
+		authorState := state.Author(author.Name())
+		if authorState.Removed {
+			if authorState.RemovedArticles < opts.MinArticles {
+				log.Println("Skipping removed author:", author.Name())
+				continue
+			}
+			// The author is wanted now, so the removed
+			// articles must be fetched again.
+			record(crawlEvent{Author: author.Name(), Reset: true})
+			authorState = state.Author(author.Name())
+		}
+		if authorState.Done && !authorState.needsRetry(opts) && !opts.Incremental {
+			log.Println("Skipping finished author:", author.Name())
+			if countArticles(authorPath) > 0 {
+				numAuthors++
+			}
+			continue
+		}
+		if total := countArticles(authorPath); opts.MaxArticles >= 0 && total >= opts.MaxArticles {
+			log.Println("Skipping author with enough articles:", author.Name())
+			if total > 0 {
+				numAuthors++
+			}
+			continue
+		}
 		log.Println("Fetching author:", author.Name())
 
-		authorPath := filepath.Join(out, author.Name())
 		if info, err := os.Stat(authorPath); os.IsNotExist(err) {
 			if err := os.Mkdir(authorPath, 0755); err != nil {
 				fmt.Fprintln(os.Stderr, "Failed to make author dir:", err)
@@ -67,33 +329,63 @@ func fetchIntoDir(s source.Source, out string, maxArt int) {
 			fmt.Fprintln(os.Stderr, "Author dir path already exists.")
 			os.Exit(1)
 		}
+		// Only finished authors can be refreshed by stopping
+		// early, since the rest may have older articles left.
+		refresh := opts.Incremental && authorState.Done
+		var newest time.Time
+		if refresh {
+			newest = newestArticle(authorPath)
+		}
 
 		stopChan := make(chan struct{})
-		arts, errChan1 := author.Articles(stopChan)
-		for i := 0; i != maxArt; i++ {
+		var arts <-chan source.Article
+		var errChan1 <-chan error
+		paged, isPaged := author.(source.PagedAuthor)
+		if isPaged && !authorState.Done && authorState.ListingPage > 1 {
+			log.Println("Resuming from listing page:", authorState.ListingPage)
+			arts, errChan1 = paged.ArticlesFrom(authorState.ListingPage, stopChan)
+		} else {
+			arts, errChan1 = author.Articles(stopChan)
+		}
+		listed := false
+		saved := countArticles(authorPath)
+		for opts.MaxArticles < 0 || saved < opts.MaxArticles {
 			art, ok := <-arts
 			if !ok {
+				listed = true
 				break
 			}
-			artPath := filepath.Join(authorPath, art.ID()+".txt")
-			if _, err := os.Stat(artPath); !os.IsNotExist(err) {
-				log.Println("Skipping article:", art.ID())
-				continue
+			if p, ok := art.(source.PagedArticle); ok && p.ListingPage() > authorState.ListingPage {
+				record(crawlEvent{Author: author.Name(), ListingPage: p.ListingPage()})
 			}
-			log.Println("Fetching article:", art.ID())
-			body, err := art.Body()
-			if err != nil {
-				// Not a fatal error because some articles might
-				// be broken while others are not.
-				log.Println("Failed to fetch body:", err)
-				continue
+			_, statErr := os.Stat(filepath.Join(authorPath, art.ID()+".txt"))
+			existed := statErr == nil
+			if refresh && (existed || authorState.fetched(art.ID())) {
+				// Listings are newest-first, so older articles
+				// were handled by previous runs. A fetched
+				// article dated after the newest local one was
+				// re-promoted, so the listing goes on past it.
+				if date, _ := art.Date(); !date.After(newest) {
+					log.Println("Reached previously fetched article:", art.ID())
+					break
+				}
 			}
-			if err := ioutil.WriteFile(artPath, []byte(body), 0755); err != nil {
-				fmt.Fprintln(os.Stderr, "Failed to write output:", err)
-				os.Exit(1)
+			outcome, errMsg := fetchArticle(art, authorPath, authorState, opts, report)
+			if outcome != "" {
+				record(crawlEvent{Author: author.Name(), Article: art.ID(), Outcome: outcome,
+					Error: errMsg})
 			}
-			if date, _ := art.Date(); !date.IsZero() {
-				os.Chtimes(artPath, date, date)
+			if outcome == outcomeOK && !existed {
+				saved++
+			}
+			if opts.Incremental && outcome == outcomeOK && !existed {
+				date, _ := art.Date()
+				var dateStr string
+				if !date.IsZero() {
+					dateStr = date.Format(time.RFC3339)
+				}
+				fmt.Fprintf(added, "%s\t%s\t%s\n", runTime,
+					filepath.Join(author.Name(), art.ID()+".txt"), dateStr)
 			}
 		}
 		close(stopChan)
@@ -101,16 +393,129 @@ func fetchIntoDir(s source.Source, out string, maxArt int) {
 			fmt.Fprintln(os.Stderr, "Error listing articles:", err)
 			os.Exit(1)
 		}
+		if listed && !authorState.Done {
+			record(crawlEvent{Author: author.Name(), Done: true})
+		}
+		total := countArticles(authorPath)
+		if listed && total < opts.MinArticles {
+			log.Println("Removing author with too few articles:", author.Name())
+			if err := os.RemoveAll(authorPath); err != nil {
+				fmt.Fprintln(os.Stderr, "Failed to remove author dir:", err)
+				os.Exit(1)
+			}
+			record(crawlEvent{Author: author.Name(), Done: true, Removed: true, Count: total})
+		} else if total > 0 {
+			numAuthors++
+		}
 	}
 	if err := <-errChan; err != nil {
 		fmt.Fprintln(os.Stderr, "Error listing authors:", err)
 		os.Exit(1)
 	}
+	if _, ok := s.(source.RestorableSource); ok && !restored && !stopped && allKeyed &&
+		!state.authorsListed {
+		record(crawlEvent{AuthorsListed: true})
+	}
+}
+
+// fetchArticle saves an article to an author's directory,
+// returning the outcome to record in the crawl state (or
+// "" if the article was skipped without being tried).
+func fetchArticle(art source.Article, authorPath string, authorState *authorState,
+	opts fetchOptions, report *os.File) (outcome, errMsg string) {
+	artPath := filepath.Join(authorPath, art.ID()+".txt")
+	if artState, ok := authorState.Articles[art.ID()]; ok &&
+		artState.finished(opts) {
+		log.Println("Skipping article:", art.ID())
+		return "", ""
+	}
+	if _, err := os.Stat(artPath); !os.IsNotExist(err) {
+		// Fetched by a run which did not record its state.
+		log.Println("Skipping article:", art.ID())
+		return outcomeOK, ""
+	}
+	if !allowDate(opts, art) {
+		// Not recorded, since the range may change.
+		log.Println("Skipping article outside date range:", art.ID())
+		return "", ""
+	}
+	if !opts.Coauthored {
+		names, err := art.Authors()
+		if err != nil {
+			log.Println("Failed to fetch authors:", err)
+			return articleOutcome(err), err.Error()
+		} else if len(names) > 1 {
+			log.Println("Skipping co-authored article:", art.ID())
+			return outcomeCoauthored, ""
+		}
+	}
+	log.Println("Fetching article:", art.ID())
+	var body string
+	var removed clean.Report
+	var err error
+	if opts.NoQuotes {
+		var doc *source.Document
+		if doc, err = art.Document(); err == nil {
+			body, removed = clean.QuotesDocument(doc)
+		}
+	} else {
+		body, err = art.Body()
+	}
+	if err == source.ErrExcluded {
+		// Not recorded, since the filters may change.
+		log.Println("Skipping excluded article:", art.ID())
+		return "", ""
+	} else if err != nil {
+		// Not a fatal error because some articles might
+		// be broken while others are not.
+		log.Println("Failed to fetch body:", err)
+		return articleOutcome(err), err.Error()
+	} else if body == "" {
+		log.Println("Skipping empty article:", art.ID())
+		return outcomeEmpty, ""
+	} else if utf8.RuneCountInString(body) < opts.MinLength {
+		// Not recorded, since the minimum may change.
+		log.Println("Skipping short article:", art.ID())
+		return "", ""
+	}
+	if err := ioutil.WriteFile(artPath, []byte(body), 0755); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to write output:", err)
+		os.Exit(1)
+	}
+	if opts.NoQuotes {
+		fmt.Fprintf(report, "%s\t%s\n", filepath.Join(filepath.Base(authorPath),
+			art.ID()+".txt"), removed)
+	}
+	if date, _ := art.Date(); !date.IsZero() {
+		os.Chtimes(artPath, date, date)
+	}
+	return outcomeOK, ""
+}
+
+// newestArticle finds the latest modification time of an
+// author's articles, which fetch sets to the article date.
+func newestArticle(authorPath string) time.Time {
+	var res time.Time
+	listing, _ := ioutil.ReadDir(authorPath)
+	for _, info := range listing {
+		if filepath.Ext(info.Name()) == ".txt" && info.ModTime().After(res) {
+			res = info.ModTime()
+		}
+	}
+	return res
 }
 
 func dieUsage() {
-	fmt.Fprintln(os.Stderr, "Usage:", os.Args[0], "fetch <source> <output_dir> [max_art]")
+	fmt.Fprintln(os.Stderr, "Usage:", os.Args[0], "fetch [flags] <source> <output_dir> [max_art]")
+	fmt.Fprintln(os.Stderr, "      ", os.Args[0], "list-authors [--count] <source>")
+	fmt.Fprintln(os.Stderr, "      ", os.Args[0], "list-articles [--count] [--dates] <source> <author>")
+	fmt.Fprintln(os.Stderr, "      ", os.Args[0], "strip [-min n] <output_dir>")
 	fmt.Fprintln(os.Stderr, "      ", os.Args[0], "help <source>")
+	fmt.Fprintln(os.Stderr, "\nFetch flags:")
+	fetchFlags(&fetchOptions{}).PrintDefaults()
+	fmt.Fprintln(os.Stderr, "\nList flags:")
+	var count, dates bool
+	listFlags("list", &count, &dates).PrintDefaults()
 
 	var sourceNames []string
 	for name := range source.Sources {
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/textprint/source"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+func TestFetchMinArticles(t *testing.T) {
+	out := t.TempDir()
+	s := &fakeSource{authors: []source.Author{
+		&fakeAuthor{name: "Alice", articles: []*fakeArticle{
+			{id: "a1", body: "First."},
+			{id: "a2", body: "Second."},
+		}},
+	}}
+
+	arts := s.authors[0].(*fakeAuthor).articles
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3, MinArticles: 3})
+	if _, err := os.Stat(filepath.Join(out, "Alice")); !os.IsNotExist(err) {
+		t.Error("expected author to be removed")
+	}
+
+	// Resuming with the same threshold must not fetch the
+	// removed author again.
+	before := fetchCounts(arts)
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3, MinArticles: 3})
+	if counts := fetchCounts(arts); !reflect.DeepEqual(counts, before) {
+		t.Errorf("removed author fetched again: %v (previously %v)", counts, before)
+	}
+	if files := outputFiles(t, out); len(files) != 0 {
+		t.Errorf("unexpected files %v", files)
+	}
+
+	// The removed articles must be fetched again once the
+	// author is wanted.
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3, MinArticles: 2})
+	if files := outputFiles(t, out); !reflect.DeepEqual(files, []string{"Alice/a1.txt", "Alice/a2.txt"}) {
+		t.Errorf("unexpected files %v", files)
+	}
+}
+
+func TestFetchMaxArticles(t *testing.T) {
+	out := t.TempDir()
+	arts := []*fakeArticle{
+		{id: "a1", body: "One."},
+		{id: "a2", body: "Two."},
+		{id: "a3", body: "Three."},
+	}
+	s := &fakeSource{authors: []source.Author{&fakeAuthor{name: "Alice", articles: arts}}}
+
+	// Articles saved by earlier runs count toward the
+	// limit, so running again adds nothing.
+	for run := 0; run < 2; run++ {
+		fetchIntoDir(s, out, fetchOptions{MaxArticles: 2, MaxAttempts: 3})
+		if files := outputFiles(t, out); !reflect.DeepEqual(files,
+			[]string{"Alice/a1.txt", "Alice/a2.txt"}) {
+			t.Fatalf("run %d: unexpected files %v", run, files)
+		}
+	}
+	if counts := fetchCounts(arts); counts[2] != 0 {
+		t.Errorf("unexpected fetches %v", counts)
+	}
+
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: 3, MaxAttempts: 3})
+	if files := outputFiles(t, out); len(files) != 3 {
+		t.Errorf("unexpected files %v", files)
+	}
+}
+
+func TestFetchResume(t *testing.T) {
+	out := t.TempDir()
+	arts := []*fakeArticle{
+		{id: "a1", body: "One.", page: 1},
+		{id: "a2", body: "Two.", page: 1},
+		{id: "a3", body: "Three.", page: 2},
+		{id: "a4", body: "Four.", page: 2},
+	}
+	author := &fakePagedAuthor{fakeAuthor: fakeAuthor{name: "Alice", articles: arts}}
+	s := &fakeSource{authors: []source.Author{author}}
+
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: 3, MaxAttempts: 3})
+	if files := outputFiles(t, out); !reflect.DeepEqual(files,
+		[]string{"Alice/a1.txt", "Alice/a2.txt", "Alice/a3.txt"}) {
+		t.Fatalf("unexpected files %v", files)
+	}
+
+	before := fetchCounts(arts)
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3})
+	if author.fromPage != 2 {
+		t.Errorf("expected to resume from page 2 but got %d", author.fromPage)
+	}
+	if files := outputFiles(t, out); len(files) != 4 {
+		t.Errorf("unexpected files %v", files)
+	}
+	after := fetchCounts(arts)
+	if !reflect.DeepEqual(after[:3], before[:3]) || after[3] == 0 {
+		t.Errorf("unexpected fetches %v (previously %v)", after, before)
+	}
+
+	// The author is finished, so nothing should be fetched.
+	author.fromPage = 0
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3})
+	if counts := fetchCounts(arts); !reflect.DeepEqual(counts, after) || author.fromPage != 0 {
+		t.Errorf("unexpected fetches %v (previously %v)", counts, after)
+	}
+}
+
+func TestFetchRestoresAuthors(t *testing.T) {
+	out := t.TempDir()
+	broken := &fakeArticle{id: "b2", body: "Two.", err: errors.New("connection reset")}
+	s := &fakeRestorableSource{fakeSource: fakeSource{authors: []source.Author{
+		&fakeAuthor{name: "Alice", articles: []*fakeArticle{{id: "a1", body: "One."}}},
+		&fakeAuthor{name: "Bob", articles: []*fakeArticle{{id: "b1", body: "One."}, broken}},
+	}}}
+
+	// Stopping early leaves the list of authors incomplete,
+	// so the next run must list them again.
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3, MaxAuthors: 1})
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3})
+	if s.listings != 2 {
+		t.Fatalf("expected 2 listings but got %d", s.listings)
+	}
+
+	broken.err = nil
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3})
+	if s.listings != 2 {
+		t.Errorf("authors listed again (%d listings)", s.listings)
+	}
+	if files := outputFiles(t, out); !reflect.DeepEqual(files,
+		[]string{"Alice/a1.txt", "Bob/b1.txt", "Bob/b2.txt"}) {
+		t.Errorf("unexpected files %v", files)
+	}
+
+	// New authors may appear between incremental runs.
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3, Incremental: true})
+	if s.listings != 3 {
+		t.Errorf("incremental run did not list authors (%d listings)", s.listings)
+	}
+}
+
+func TestFetchRetries(t *testing.T) {
+	tests := []struct {
+		article  fakeArticle
+		opts     fetchOptions
+		outcome  string
+		attempts int
+	}{
+		{
+			fakeArticle{err: errors.New("connection reset")},
+			fetchOptions{MaxAttempts: 2},
+			outcomeError,
+			2,
+		},
+		{
+			fakeArticle{err: &source.HTTPError{URL: "http://example.com", StatusCode: 503}},
+			fetchOptions{MaxAttempts: 3},
+			"http-503",
+			3,
+		},
+		{
+			fakeArticle{err: source.ErrSubscriberOnly},
+			fetchOptions{MaxAttempts: 3},
+			outcomeSubscriberOnly,
+			1,
+		},
+		{
+			fakeArticle{err: source.ErrSubscriberOnly},
+			fetchOptions{MaxAttempts: 3, Cookies: "cookies.txt"},
+			outcomeSubscriberOnly,
+			3,
+		},
+		{
+			fakeArticle{body: ""},
+			fetchOptions{MaxAttempts: 3},
+			outcomeEmpty,
+			1,
+		},
+		{
+			fakeArticle{body: "Shared.", authors: []string{"Alice", "Bob"}},
+			fetchOptions{MaxAttempts: 3},
+			outcomeCoauthored,
+			1,
+		},
+	}
+	for i, test := range tests {
+		out := t.TempDir()
+		art := test.article
+		art.id = "a1"
+		s := &fakeSource{authors: []source.Author{
+			&fakeAuthor{name: "Alice", articles: []*fakeArticle{&art}},
+		}}
+		test.opts.MaxArticles = -1
+		for run := 0; run < 4; run++ {
+			fetchIntoDir(s, out, test.opts)
+		}
+
+		state, err := openCrawlState(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		artState := state.Author("Alice").Articles["a1"]
+		state.Close()
+		if artState == nil {
+			t.Errorf("test %d: no state recorded", i)
+			continue
+		}
+		if artState.Outcome != test.outcome || artState.Attempts != test.attempts {
+			t.Errorf("test %d: got outcome %s after %d attempts", i, artState.Outcome,
+				artState.Attempts)
+		}
+	}
+}
+
+func TestFetchIncremental(t *testing.T) {
+	out := t.TempDir()
+	day := func(d int) time.Time {
+		return time.Date(2017, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	old := []*fakeArticle{
+		{id: "a2", body: "Two.", date: day(2)},
+		{id: "a1", body: "One.", date: day(1)},
+	}
+	author := &fakeAuthor{name: "Alice", articles: old}
+	s := &fakeSource{authors: []source.Author{author}}
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3})
+
+	before := fetchCounts(old)
+	newArt := &fakeArticle{id: "a3", body: "Three.", date: day(3)}
+	author.articles = append([]*fakeArticle{newArt}, old...)
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3, Incremental: true})
+
+	if files := outputFiles(t, out); len(files) != 3 {
+		t.Errorf("unexpected files %v", files)
+	}
+	if counts := fetchCounts(old); !reflect.DeepEqual(counts, before) {
+		t.Errorf("old articles fetched again: %v (previously %v)", counts, before)
+	}
+	if old[0].dates != 2 || old[1].dates != 1 {
+		t.Errorf("unexpected date reads %d, %d", old[0].dates, old[1].dates)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(out, addedFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 || !strings.HasSuffix(lines[0],
+		"\tAlice/a3.txt\t"+day(3).Format(time.RFC3339)) {
+		t.Errorf("unexpected added list %q", data)
+	}
+
+	// Nothing is new, so nothing should be added.
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3, Incremental: true})
+	if data1, _ := ioutil.ReadFile(filepath.Join(out, addedFile)); string(data1) != string(data) {
+		t.Errorf("unexpected added list %q", data1)
+	}
+
+	// A fetched article which is re-promoted with a newer
+	// date must not end the refresh.
+	promoted := old[1]
+	promoted.date = day(5)
+	newer := &fakeArticle{id: "a4", body: "Four.", date: day(4)}
+	author.articles = []*fakeArticle{promoted, newer, newArt, old[0]}
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3, Incremental: true})
+	if files := outputFiles(t, out); len(files) != 4 {
+		t.Errorf("unexpected files %v", files)
+	}
+}
+
+func TestFetchIncrementalUnfinished(t *testing.T) {
+	out := t.TempDir()
+	arts := []*fakeArticle{
+		{id: "a1", body: "One.", page: 1},
+		{id: "a2", body: "Two.", page: 1},
+		{id: "a3", body: "Three.", page: 2},
+		{id: "a4", body: "Four.", page: 2},
+	}
+	author := &fakePagedAuthor{fakeAuthor: fakeAuthor{name: "Alice", articles: arts}}
+	s := &fakeSource{authors: []source.Author{author}}
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: 3, MaxAttempts: 3})
+
+	// The author was never finished, so the refresh must
+	// resume the listing rather than stop at a1.
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3, Incremental: true})
+	if author.fromPage != 2 {
+		t.Errorf("expected to resume from page 2 but got %d", author.fromPage)
+	}
+	if files := outputFiles(t, out); len(files) != 4 {
+		t.Errorf("unexpected files %v", files)
+	}
+}
+
+func TestFetchQuoteReport(t *testing.T) {
+	out := t.TempDir()
+	s := &fakeSource{authors: []source.Author{
+		&fakeAuthor{name: "Alice", articles: []*fakeArticle{
+			{id: "a1", body: "A body which is long enough to keep."},
+			{id: "a2", body: "Too short."},
+			{id: "a3", body: ""},
+		}},
+	}}
+	fetchIntoDir(s, out, fetchOptions{MaxArticles: -1, MaxAttempts: 3, MinLength: 20,
+		NoQuotes: true})
+	data, err := ioutil.ReadFile(filepath.Join(out, quoteReportFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "Alice/a1.txt\t") {
+		t.Errorf("unexpected report %q", data)
+	}
+}
+
+type fakeSource struct {
+	authors []source.Author
+}
+
+func (f *fakeSource) Help() string {
+	return ""
+}
+
+func (f *fakeSource) Authors(stop <-chan struct{}) (<-chan source.Author, <-chan error) {
+	res := make(chan source.Author)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(res)
+		defer close(errChan)
+		for _, a := range f.authors {
+			select {
+			case res <- a:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return res, errChan
+}
+
+// fakeRestorableSource counts the times its authors are
+// listed.
+type fakeRestorableSource struct {
+	fakeSource
+	listings int
+}
+
+func (f *fakeRestorableSource) Authors(stop <-chan struct{}) (<-chan source.Author,
+	<-chan error) {
+	f.listings++
+	return f.fakeSource.Authors(stop)
+}
+
+func (f *fakeRestorableSource) RestoreAuthor(name, key string) (source.Author, error) {
+	for _, a := range f.authors {
+		if a.(source.KeyedAuthor).Key() == key {
+			return a, nil
+		}
+	}
+	return nil, errors.New("unknown author: " + name)
+}
+
+type fakeAuthor struct {
+	name     string
+	articles []*fakeArticle
+}
+
+func (f *fakeAuthor) Name() string {
+	return f.name
+}
+
+func (f *fakeAuthor) Key() string {
+	return "key:" + f.name
+}
+
+func (f *fakeAuthor) Articles(stop <-chan struct{}) (<-chan source.Article, <-chan error) {
+	return streamArticles(f.articles, stop)
+}
+
+func streamArticles(arts []*fakeArticle, stop <-chan struct{}) (<-chan source.Article,
+	<-chan error) {
+	res := make(chan source.Article)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(res)
+		defer close(errChan)
+		for _, a := range arts {
+			select {
+			case res <- a:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return res, errChan
+}
+
+// fakePagedAuthor lists articles on the pages given by
+// their page fields.
+type fakePagedAuthor struct {
+	fakeAuthor
+	fromPage int
+}
+
+func (f *fakePagedAuthor) ArticlesFrom(page int, stop <-chan struct{}) (<-chan source.Article,
+	<-chan error) {
+	f.fromPage = page
+	var arts []*fakeArticle
+	for _, a := range f.articles {
+		if a.page >= page {
+			arts = append(arts, a)
+		}
+	}
+	return streamArticles(arts, stop)
+}
+
+// fakeArticle counts the calls which would fetch an
+// article's page from a real source.
+type fakeArticle struct {
+	id      string
+	body    string
+	err     error
+	date    time.Time
+	authors []string
+	page    int
+
+	fetches int
+	dates   int
+}
+
+func (f *fakeArticle) ListingPage() int {
+	return f.page
+}
+
+func (f *fakeArticle) ID() string {
+	return f.id
+}
+
+func (f *fakeArticle) URL() string {
+	return "http://example.com/" + f.id
+}
+
+func (f *fakeArticle) Body() (string, error) {
+	f.fetches++
+	return f.body, f.err
+}
+
+func (f *fakeArticle) Document() (*source.Document, error) {
+	f.fetches++
+	return source.PlainDocument(f.body), f.err
+}
+
+func (f *fakeArticle) Date() (time.Time, error) {
+	f.dates++
+	return f.date, nil
+}
+
+func (f *fakeArticle) Authors() ([]string, error) {
+	f.fetches++
+	if f.authors == nil {
+		return []string{"Author"}, nil
+	}
+	return f.authors, nil
+}
+
+// fetchCounts lists the number of fetches of each
+// article.
+func fetchCounts(arts []*fakeArticle) []int {
+	var res []int
+	for _, a := range arts {
+		res = append(res, a.fetches)
+	}
+	return res
+}
+
+// outputFiles lists the articles saved in an output
+// directory, relative to the directory.
+func outputFiles(t *testing.T, out string) []string {
+	var res []string
+	err := filepath.Walk(out, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if filepath.Ext(path) == ".txt" {
+			rel, _ := filepath.Rel(out, path)
+			res = append(res, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/unixpickle/textprint/source"
+)
+
+// crawlStateFile is the file in the output directory
+// which records the progress of a crawl.
+//
+// It is a journal of JSON events, one per line, so that
+// progress is kept even if fetch is killed.
+const crawlStateFile = "crawl_state.jsonl"
+
+// Article outcomes recorded in the crawl state.
+// HTTP errors are recorded as "http-<status>".
+const (
+	outcomeOK             = "ok"
+	outcomeEmpty          = "empty"
+	outcomeCoauthored     = "coauthored"
+	outcomeSubscriberOnly = "subscriber-only"
+	outcomeError          = "error"
+)
+
+// crawlEvent is one line of the crawl state file.
+type crawlEvent struct {
+	Author string `json:"author"`
+
+	// ListingPage is set when the author's listing reaches
+	// a new page.
+	ListingPage int `json:"listing_page,omitempty"`
+
+	// Article and Outcome are set when an article is tried.
+	Article string `json:"article,omitempty"`
+	Outcome string `json:"outcome,omitempty"`
+	Error   string `json:"error,omitempty"`
+
+	// Done is set when every article has been listed.
+	Done bool `json:"done,omitempty"`
+
+	// Removed is set when the author's directory is removed
+	// for having too few articles, and Count is the number
+	// of articles it had.
+	Removed bool `json:"removed,omitempty"`
+	Count   int  `json:"count,omitempty"`
+
+	// Reset is set when a removed author is wanted again,
+	// discarding the author's earlier events.
+	Reset bool `json:"reset,omitempty"`
+
+	// Key is set when an author is first listed by a
+	// source which can restore it from the key.
+	Key string `json:"key,omitempty"`
+
+	// AuthorsListed is set, without an author, once every
+	// author of a source has been listed with a key.
+	AuthorsListed bool `json:"authors_listed,omitempty"`
+}
+
+// crawlState tracks the progress of every author.
+type crawlState struct {
+	file    *os.File
+	authors map[string]*authorState
+
+	// keys and listed record the authors with keys in the
+	// order they were listed, and authorsListed is set once
+	// they are complete.
+	keys          map[string]string
+	listed        []string
+	authorsListed bool
+}
+
+type authorState struct {
+	Done        bool
+	ListingPage int
+	Articles    map[string]*articleState
+
+	// Removed is set when the author's directory was removed
+	// for having RemovedArticles articles, which was too
+	// few.
+	Removed         bool
+	RemovedArticles int
+}
+
+type articleState struct {
+	Outcome  string
+	Error    string
+	Attempts int
+}
+
+// openCrawlState reads the crawl state in an output
+// directory and opens it to record further progress.
+func openCrawlState(dir string) (*crawlState, error) {
+	path := filepath.Join(dir, crawlStateFile)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	res := &crawlState{file: f, authors: map[string]*authorState{}, keys: map[string]string{}}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var event crawlEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// The last line may be cut off if fetch was killed
+			// while writing it.
+			continue
+		}
+		res.apply(&event)
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	// Terminate a cut-off line so that new events are not
+	// appended to it.
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			f.Write([]byte{'\n'})
+		}
+	}
+	return res, nil
+}
+
+// Close closes the state file.
+func (c *crawlState) Close() error {
+	return c.file.Close()
+}
+
+// Author gets the state for an author, creating it if
+// necessary.
+func (c *crawlState) Author(name string) *authorState {
+	if a, ok := c.authors[name]; ok {
+		return a
+	}
+	a := &authorState{ListingPage: 1, Articles: map[string]*articleState{}}
+	c.authors[name] = a
+	return a
+}
+
+// Record applies an event and appends it to the file.
+func (c *crawlState) Record(event crawlEvent) error {
+	c.apply(&event)
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = c.file.Write(append(data, '\n'))
+	return err
+}
+
+func (c *crawlState) apply(event *crawlEvent) {
+	if event.AuthorsListed {
+		c.authorsListed = true
+		return
+	}
+	if event.Key != "" {
+		if _, ok := c.keys[event.Author]; !ok {
+			c.listed = append(c.listed, event.Author)
+		}
+		c.keys[event.Author] = event.Key
+	}
+	if event.Reset {
+		delete(c.authors, event.Author)
+		return
+	}
+	a := c.Author(event.Author)
+	if event.ListingPage > a.ListingPage {
+		a.ListingPage = event.ListingPage
+	}
+	if event.Article != "" {
+		art, ok := a.Articles[event.Article]
+		if !ok {
+			art = &articleState{}
+			a.Articles[event.Article] = art
+		}
+		art.Outcome = event.Outcome
+		art.Error = event.Error
+		art.Attempts++
+	}
+	if event.Done {
+		a.Done = true
+	}
+	if event.Removed {
+		a.Removed = true
+		a.RemovedArticles = event.Count
+	}
+}
+
+// crawlAuthors lists the authors of a source, or restores
+// them from the state if an earlier run listed them all.
+// It reports whether the authors were restored.
+//
+// Incremental runs always list the authors, since new ones
+// may have appeared.
+func crawlAuthors(s source.Source, state *crawlState, opts fetchOptions,
+	stop <-chan struct{}) (<-chan source.Author, <-chan error, bool) {
+	restorable, ok := s.(source.RestorableSource)
+	if !ok || !state.authorsListed || opts.Incremental {
+		authors, errChan := s.Authors(stop)
+		return authors, errChan, false
+	}
+
+	var names, keys []string
+	for _, name := range state.listed {
+		names = append(names, name)
+		keys = append(keys, state.keys[name])
+	}
+	authChan := make(chan source.Author, 1)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(authChan)
+		defer close(errChan)
+		for i, name := range names {
+			author, err := restorable.RestoreAuthor(name, keys[i])
+			if err != nil {
+				errChan <- err
+				return
+			}
+			select {
+			case <-stop:
+				return
+			case authChan <- author:
+			}
+		}
+	}()
+	return authChan, errChan, true
+}
+
+// fetched checks if an article was fetched or found to
+// be empty by a previous run.
+func (a *authorState) fetched(id string) bool {
+	art, ok := a.Articles[id]
+	return ok && (art.Outcome == outcomeOK || art.Outcome == outcomeEmpty)
+}
+
+// needsRetry checks if any of an author's articles
+// should be tried again.
+func (a *authorState) needsRetry(opts fetchOptions) bool {
+	for _, art := range a.Articles {
+		if !art.finished(opts) {
+			return true
+		}
+	}
+	return false
+}
+
+// finished checks if an article needs no more attempts.
+//
+// Failed articles are tried up to opts.MaxAttempts times
+// in total.
+// Subscriber-only articles are only retried when cookies
+// are in use, and co-authored articles are tried again
+// once they are wanted.
+func (a *articleState) finished(opts fetchOptions) bool {
+	switch a.Outcome {
+	case outcomeOK, outcomeEmpty:
+		return true
+	case outcomeCoauthored:
+		return !opts.Coauthored
+	case outcomeSubscriberOnly:
+		if opts.Cookies == "" {
+			return true
+		}
+	}
+	return a.Attempts >= opts.MaxAttempts
+}
+
+// articleOutcome classifies the error from fetching an
+// article.
+func articleOutcome(err error) string {
+	if err == source.ErrSubscriberOnly {
+		return outcomeSubscriberOnly
+	} else if httpErr, ok := err.(*source.HTTPError); ok {
+		return "http-" + strconv.Itoa(httpErr.StatusCode)
+	}
+	return outcomeError
+}
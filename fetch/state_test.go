@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCrawlState(t *testing.T) {
+	dir := t.TempDir()
+	state, err := openCrawlState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := []crawlEvent{
+		{Author: "Alice", Key: "alice"},
+		{Author: "Bob", Key: "bob"},
+		{AuthorsListed: true},
+		{Author: "Alice", ListingPage: 2},
+		{Author: "Alice", Article: "a1", Outcome: outcomeOK},
+		{Author: "Alice", Article: "a2", Outcome: "http-500", Error: "status 500"},
+		{Author: "Alice", Article: "a2", Outcome: outcomeError, Error: "timeout"},
+		{Author: "Alice", Done: true},
+		{Author: "Bob", Article: "b1", Outcome: outcomeOK},
+		{Author: "Bob", Done: true},
+		{Author: "Bob", Reset: true},
+		{Author: "Dave", Done: true, Removed: true, Count: 1},
+	}
+	for _, event := range events {
+		if err := state.Record(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state.Close()
+
+	// Simulate a run which was killed mid-write.
+	path := filepath.Join(dir, crawlStateFile)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(`{"author":"Alice","arti`))
+	f.Close()
+
+	state, err = openCrawlState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.Record(crawlEvent{Author: "Carol", Done: true}); err != nil {
+		t.Fatal(err)
+	}
+	state.Close()
+
+	state, err = openCrawlState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer state.Close()
+
+	alice := state.Author("Alice")
+	if !alice.Done || alice.ListingPage != 2 || !alice.fetched("a1") || alice.fetched("a2") {
+		t.Errorf("unexpected state for Alice: %+v", alice)
+	}
+	if a2 := alice.Articles["a2"]; a2.Attempts != 2 || a2.Outcome != outcomeError ||
+		a2.Error != "timeout" {
+		t.Errorf("unexpected state for a2: %+v", a2)
+	}
+	if !alice.needsRetry(fetchOptions{MaxAttempts: 3}) ||
+		alice.needsRetry(fetchOptions{MaxAttempts: 2}) {
+		t.Error("unexpected retry decision")
+	}
+	if bob := state.Author("Bob"); bob.Done || len(bob.Articles) != 0 {
+		t.Errorf("state for Bob was not reset: %+v", bob)
+	}
+	if dave := state.Author("Dave"); !dave.Done || !dave.Removed || dave.RemovedArticles != 1 {
+		t.Errorf("unexpected state for Dave: %+v", dave)
+	}
+	if !reflect.DeepEqual(state.listed, []string{"Alice", "Bob"}) || state.keys["Bob"] != "bob" ||
+		!state.authorsListed {
+		t.Errorf("unexpected listed authors %v (%v)", state.listed, state.authorsListed)
+	}
+	if !state.Author("Carol").Done {
+		t.Error("event after cut-off line was lost")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[len(data)-1] != '\n' {
+		t.Error("state file does not end with a newline")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/unixpickle/textprint/clean"
+)
+
+// stripBoilerplate removes lines which appear in at least
+// minArticles of the articles in an output directory.
+func stripBoilerplate(dir string, minArticles int) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*.txt"))
+	if err != nil {
+		return err
+	}
+
+	detector := clean.NewBoilerplate(minArticles)
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		detector.Add(string(data))
+	}
+
+	lines, counts := detector.Lines()
+	for i, line := range lines {
+		log.Printf("Boilerplate (%d articles): %s", counts[i], line)
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		stripped, removed := detector.Strip(string(data))
+		if removed == 0 {
+			continue
+		}
+		log.Printf("Stripped %d lines from: %s", removed, path)
+		if err := ioutil.WriteFile(path, []byte(stripped), info.Mode()); err != nil {
+			return err
+		}
+		// Keep the article dates set by fetch.
+		os.Chtimes(path, info.ModTime(), info.ModTime())
+	}
+	return nil
+}
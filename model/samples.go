@@ -15,13 +15,19 @@ type Samples struct {
 	AuthorNames []string
 }
 
+// A Cleaner transforms the text of an article before it
+// is used as a sample.
+// The path identifies the article's file.
+type Cleaner func(path, text string) string
+
 // ReadSamples reads a set of samples from a directory,
 // where each sub-directory corresponds to an author and
 // each .txt file inside said directory corresponds to an
 // article.
 //
-// Any article longer than maxLen is truncated.
-func ReadSamples(dir string, maxLen int) (*Samples, error) {
+// The cleaners are applied to each article in order, and
+// any article longer than maxLen is then truncated.
+func ReadSamples(dir string, maxLen int, cleaners ...Cleaner) (*Samples, error) {
 	listing, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return nil, err
@@ -39,10 +45,14 @@ func ReadSamples(dir string, maxLen int) (*Samples, error) {
 		var arts []string
 		for _, artItem := range sub {
 			if filepath.Ext(artItem.Name()) == ".txt" {
-				contents, err := ioutil.ReadFile(filepath.Join(dirPath, artItem.Name()))
+				artPath := filepath.Join(dirPath, artItem.Name())
+				contents, err := ioutil.ReadFile(artPath)
 				if err != nil {
 					return nil, err
 				}
+				for _, c := range cleaners {
+					contents = []byte(c(artPath, string(contents)))
+				}
 				if len(contents) > maxLen {
 					contents = contents[:maxLen]
 				}
@@ -0,0 +1,385 @@
+package source
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Chat is a Source that reads messages from Slack exports
+// and IRC logs, grouping them by user.
+//
+// Each message is an article by default, but consecutive
+// messages from the same user in a channel can be joined
+// into a single conversation turn.
+// Mentions, URLs and code snippets are removed from the
+// text.
+type Chat struct{}
+
+// Help returns the usage information for this Source.
+func (_ Chat) Help() string {
+	return "Read messages from Slack exports and IRC logs.\n" +
+		"Environment variables:\n" +
+		" CHAT_SLACK_DIR    directory of an unzipped Slack export\n" +
+		" CHAT_IRC_FILES    comma-separated IRC log paths or globs\n" +
+		" CHAT_TURNS        set to 1 to join consecutive messages into turns\n" +
+		" CHAT_MIN_LENGTH   minimum article length in bytes (default 1)\n" +
+		"At least one of CHAT_SLACK_DIR and CHAT_IRC_FILES is required.\n" +
+		"IRC logs may be in irssi, WeeChat or ZNC format. For formats\n" +
+		"without dates on each line, the date is taken from \"Day changed\"\n" +
+		"lines or from a YYYY-MM-DD date in the file name."
+}
+
+// Authors reads every log and then produces the users in
+// alphabetical order.
+func (_ Chat) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+	authChan := make(chan Author, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(authChan)
+		defer close(errChan)
+
+		slackDir, ircFiles := os.Getenv("CHAT_SLACK_DIR"), os.Getenv("CHAT_IRC_FILES")
+		if slackDir == "" && ircFiles == "" {
+			errChan <- errors.New("missing CHAT_SLACK_DIR or CHAT_IRC_FILES")
+			return
+		}
+		minLength, err := envInt("CHAT_MIN_LENGTH", 1)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		var messages []*chatMessage
+		if slackDir != "" {
+			msgs, err := chatReadSlack(slackDir)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			messages = append(messages, msgs...)
+		}
+		if ircFiles != "" {
+			paths, err := globList("CHAT_IRC_FILES")
+			if err != nil {
+				errChan <- err
+				return
+			}
+			for _, path := range paths {
+				msgs, err := chatReadIRC(path)
+				if err != nil {
+					errChan <- err
+					return
+				}
+				messages = append(messages, msgs...)
+			}
+		}
+		if os.Getenv("CHAT_TURNS") == "1" {
+			messages = chatJoinTurns(messages)
+		}
+
+		authors := map[string]*chatAuthor{}
+		for _, msg := range messages {
+			msg.text = chatCleanText(msg.text)
+			if len(msg.text) < minLength {
+				continue
+			}
+			if authors[msg.user] == nil {
+				authors[msg.user] = &chatAuthor{name: msg.user}
+			}
+			authors[msg.user].messages = append(authors[msg.user].messages, msg)
+		}
+
+		var names []string
+		for name := range authors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			select {
+			case <-stop:
+				return
+			case authChan <- authors[name]:
+			}
+		}
+	}()
+
+	return authChan, errChan
+}
+
+// chatMessage is a single message, or a turn made up of
+// several messages.
+type chatMessage struct {
+	channel string
+	user    string
+	text    string
+	time    time.Time
+}
+
+// chatJoinTurns merges runs of messages from the same user
+// in the same channel.
+// Messages from each log are assumed to be in order.
+func chatJoinTurns(messages []*chatMessage) []*chatMessage {
+	var res []*chatMessage
+	last := map[string]*chatMessage{}
+	for _, msg := range messages {
+		if prev := last[msg.channel]; prev != nil && prev.user == msg.user {
+			prev.text += "\n" + msg.text
+			continue
+		}
+		joined := *msg
+		last[msg.channel] = &joined
+		res = append(res, &joined)
+	}
+	return res
+}
+
+var (
+	chatSlackMarkup = regexp.MustCompile(`<[@#!][^>]*>`)
+	chatSlackLink   = regexp.MustCompile(`<[a-z]+:[^>]*>`)
+	chatURL         = regexp.MustCompile(`(?i)\b(https?://|www\.)\S+`)
+	chatCodeBlock   = regexp.MustCompile("(?s)```.*?```")
+	chatInlineCode  = regexp.MustCompile("`[^`\n]*`")
+	chatMention     = regexp.MustCompile(`(^|\s)@[\w.\-]+[:,]?`)
+)
+
+// chatCleanText removes mentions, URLs and code snippets
+// from a message, collapsing the whitespace on each line.
+func chatCleanText(text string) string {
+	text = chatCodeBlock.ReplaceAllString(text, " ")
+	text = chatInlineCode.ReplaceAllString(text, " ")
+	text = chatSlackMarkup.ReplaceAllString(text, " ")
+	text = chatSlackLink.ReplaceAllString(text, " ")
+	text = html.UnescapeString(text)
+	text = chatURL.ReplaceAllString(text, " ")
+	text = chatMention.ReplaceAllString(text, "$1")
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+type slackMessage struct {
+	Type    string `json:"type"`
+	Subtype string `json:"subtype"`
+	User    string `json:"user"`
+	BotID   string `json:"bot_id"`
+	Text    string `json:"text"`
+	TS      string `json:"ts"`
+}
+
+// chatReadSlack reads every channel in a Slack export.
+// Bot messages and notices such as channel joins are
+// skipped.
+func chatReadSlack(dir string) ([]*chatMessage, error) {
+	var users []struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		RealName string `json:"real_name"`
+		Profile  struct {
+			RealName string `json:"real_name"`
+		} `json:"profile"`
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "users.json"))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, errors.New("users.json: " + err.Error())
+	}
+	names := map[string]string{}
+	for _, u := range users {
+		names[u.ID] = u.Name
+		if u.Profile.RealName != "" {
+			names[u.ID] = u.Profile.RealName
+		} else if u.RealName != "" {
+			names[u.ID] = u.RealName
+		}
+	}
+
+	// Channel directories hold one file per day, so sorting
+	// the paths keeps each channel in order.
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var res []*chatMessage
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var day []slackMessage
+		if err := json.Unmarshal(data, &day); err != nil {
+			return nil, errors.New(path + ": " + err.Error())
+		}
+		channel := "slack:" + filepath.Base(filepath.Dir(path))
+		for _, msg := range day {
+			if msg.Type != "message" || msg.Subtype != "" || msg.BotID != "" ||
+				names[msg.User] == "" {
+				continue
+			}
+			res = append(res, &chatMessage{
+				channel: channel,
+				user:    names[msg.User],
+				text:    msg.Text,
+				time:    slackTime(msg.TS),
+			})
+		}
+	}
+	return res, nil
+}
+
+func slackTime(ts string) time.Time {
+	secs, err := strconv.ParseFloat(ts, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(int64(secs), 0).UTC()
+}
+
+var (
+	// WeeChat: "2016-11-14 09:15:00\tnick\tmessage".
+	ircWeeChatLine = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\t([^\t]*)\t(.*)$`)
+
+	// irssi: "09:15 < nick> message" and ZNC: "[09:15:00] <nick> message".
+	ircNickLine = regexp.MustCompile(`^\[?(\d{2}:\d{2}(?::\d{2})?)\]? <\s*[@+%&~]?([^>\s]+)> (.*)$`)
+
+	ircDayChanged = regexp.MustCompile(`^--- (?:Day changed|Log opened) \w{3} (\w{3} \d{2}(?: \d{2}:\d{2}:\d{2})? \d{4})`)
+	ircFileDate   = regexp.MustCompile(`(\d{4})-?(\d{2})-?(\d{2})`)
+)
+
+// chatReadIRC reads the messages in an IRC log, skipping
+// actions, joins and other events.
+// A leading "nick:" addressing a known user is treated
+// as a mention and removed.
+func chatReadIRC(path string) ([]*chatMessage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var day time.Time
+	if match := ircFileDate.FindStringSubmatch(filepath.Base(path)); match != nil {
+		day, _ = time.Parse("20060102", match[1]+match[2]+match[3])
+	}
+
+	var res []*chatMessage
+	nicks := map[string]bool{}
+	channel := "irc:" + path
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		var msg *chatMessage
+		if match := ircWeeChatLine.FindStringSubmatch(line); match != nil {
+			if strings.Trim(match[2], " -<>=!*") == "" {
+				// Prefixes such as "-->", "<--", "--" and " *"
+				// mark events and actions.
+				continue
+			}
+			nick := strings.TrimLeft(match[2], "@+%&~")
+			t, _ := time.Parse("2006-01-02 15:04:05", match[1])
+			msg = &chatMessage{user: nick, text: match[3], time: t}
+		} else if match := ircNickLine.FindStringSubmatch(line); match != nil {
+			msg = &chatMessage{user: match[2], text: match[3], time: ircTime(day, match[1])}
+		} else if match := ircDayChanged.FindStringSubmatch(line); match != nil {
+			fields := strings.Fields(match[1])
+			day, _ = time.Parse("Jan 02 2006", fields[0]+" "+fields[1]+" "+fields[len(fields)-1])
+			continue
+		} else {
+			continue
+		}
+
+		nicks[msg.user] = true
+		if i := strings.IndexAny(msg.text, ":,"); i > 0 && nicks[msg.text[:i]] {
+			msg.text = msg.text[i+1:]
+		}
+		msg.channel = channel
+		res = append(res, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New(path + ": " + err.Error())
+	}
+	return res, nil
+}
+
+func ircTime(day time.Time, clock string) time.Time {
+	if day.IsZero() {
+		return day
+	}
+	if len(clock) == 5 {
+		clock += ":00"
+	}
+	t, err := time.Parse("15:04:05", clock)
+	if err != nil {
+		return day
+	}
+	return day.Add(time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second)
+}
+
+type chatAuthor struct {
+	name     string
+	messages []*chatMessage
+}
+
+func (c *chatAuthor) Name() string {
+	return c.name
+}
+
+func (c *chatAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan error) {
+	artChan := make(chan Article, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(artChan)
+		defer close(errChan)
+		for _, m := range c.messages {
+			select {
+			case <-stop:
+				return
+			case artChan <- m:
+			}
+		}
+	}()
+
+	return artChan, errChan
+}
+
+func (c *chatMessage) ID() string {
+	return hashID(c.channel + ":" + c.user + ":" + c.time.String() + ":" + c.text)
+}
+
+func (c *chatMessage) Body() (string, error) {
+	return c.text, nil
+}
+
+func (c *chatMessage) Document() (*Document, error) {
+	return bodyDocument(c)
+}
+
+func (c *chatMessage) Date() (time.Time, error) {
+	return c.time, nil
+}
+
+func (c *chatMessage) Authors() ([]string, error) {
+	return []string{c.user}, nil
+}
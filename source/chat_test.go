@@ -0,0 +1,61 @@
+package source
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestChat(t *testing.T) {
+	dir := filepath.Join("testdata", "chat")
+	setEnv(t, map[string]string{
+		"CHAT_SLACK_DIR": filepath.Join(dir, "slack"),
+		"CHAT_IRC_FILES": filepath.Join(dir, "irc", "*.log"),
+	})
+
+	expected := map[string][]string{
+		"Jane Doe": {"did you see the draft? It's at .", "I think is fine & simple."},
+		"John Roe": {"Looks good:\nShip it."},
+		"jdoe":     {"good morning everyone", "still here", "another day, another log"},
+		"roe":      {"morning, see for the notes", "hello from znc"},
+	}
+	expectedDays := map[string][]int{
+		"Jane Doe": {14, 14},
+		"John Roe": {14},
+		"jdoe":     {14, 15, 16},
+		"roe":      {14, 17},
+	}
+	actual, days := chatCollect(t)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected messages: %#v", actual)
+	}
+	if !reflect.DeepEqual(days, expectedDays) {
+		t.Errorf("unexpected days: %v", days)
+	}
+
+	setEnv(t, map[string]string{
+		"CHAT_IRC_FILES":  "",
+		"CHAT_TURNS":      "1",
+		"CHAT_MIN_LENGTH": "30",
+	})
+	expected = map[string][]string{
+		"Jane Doe": {"did you see the draft? It's at .\nI think is fine & simple."},
+	}
+	if actual, _ := chatCollect(t); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected turns: %#v", actual)
+	}
+}
+
+func chatCollect(t *testing.T) (map[string][]string, map[string][]int) {
+	bodies := map[string][]string{}
+	days := map[string][]int{}
+	for _, author := range collectAuthors(t, Chat{}) {
+		for _, art := range collectArticles(t, author) {
+			body, _ := art.Body()
+			date, _ := art.Date()
+			bodies[author.Name()] = append(bodies[author.Name()], body)
+			days[author.Name()] = append(days[author.Name()], date.Day())
+		}
+	}
+	return bodies, days
+}
@@ -0,0 +1,265 @@
+package source
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// A CookieFile is an http.CookieJar backed by a
+// Netscape-format cookie file, as exported by browser
+// extensions and used by curl and wget.
+//
+// Cookies set by servers are written back to the file, so
+// that a login session persists between runs.
+type CookieFile struct {
+	path string
+	jar  *cookiejar.Jar
+
+	lock    sync.Mutex
+	entries map[string]*cookieEntry
+}
+
+type cookieEntry struct {
+	domain     string
+	subdomains bool
+	path       string
+	secure     bool
+	httpOnly   bool
+	expires    time.Time
+	name       string
+	value      string
+}
+
+// OpenCookieFile loads the cookies from a file.
+// If the file does not exist, the jar starts out empty
+// and the file is created when a cookie is set.
+func OpenCookieFile(path string) (*CookieFile, error) {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, err
+	}
+	res := &CookieFile{path: path, jar: jar, entries: map[string]*cookieEntry{}}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return res, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		entry, err := parseCookieLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, lineNum, err)
+		} else if entry != nil && !entry.expired() {
+			res.entries[entry.key()] = entry
+			res.jar.SetCookies(entry.url(), []*http.Cookie{entry.cookie()})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UseCookieFile makes HTTPClient send and save cookies
+// with a cookie file.
+func UseCookieFile(path string) error {
+	jar, err := OpenCookieFile(path)
+	if err != nil {
+		return err
+	}
+	client := *HTTPClient
+	client.Jar = jar
+	HTTPClient = &client
+	return nil
+}
+
+// Cookies returns the cookies to send in a request for u.
+func (c *CookieFile) Cookies(u *url.URL) []*http.Cookie {
+	return c.jar.Cookies(u)
+}
+
+// SetCookies handles the cookies in a response from u,
+// saving the file if any of them changed.
+// Errors saving the file are logged rather than returned,
+// since the cookies remain usable for the rest of the run.
+func (c *CookieFile) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	c.jar.SetCookies(u, cookies)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var changed bool
+	for _, cookie := range cookies {
+		entry := &cookieEntry{
+			domain:   u.Hostname(),
+			path:     cookie.Path,
+			secure:   cookie.Secure,
+			httpOnly: cookie.HttpOnly,
+			expires:  cookie.Expires,
+			name:     cookie.Name,
+			value:    cookie.Value,
+		}
+		if cookie.Domain != "" {
+			entry.domain = strings.TrimPrefix(cookie.Domain, ".")
+			entry.subdomains = true
+		}
+		if entry.path == "" || !strings.HasPrefix(entry.path, "/") {
+			entry.path = "/"
+		}
+		if cookie.MaxAge > 0 {
+			entry.expires = time.Now().Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+		old, exists := c.entries[entry.key()]
+		if cookie.MaxAge < 0 || entry.expired() {
+			if exists {
+				delete(c.entries, entry.key())
+				changed = true
+			}
+		} else if !exists || !old.equal(entry) {
+			c.entries[entry.key()] = entry
+			changed = true
+		}
+	}
+	if changed {
+		if err := c.save(); err != nil {
+			log.Println("failed to save cookie file:", err)
+		}
+	}
+}
+
+// save writes the file, replacing it atomically.
+func (c *CookieFile) save() error {
+	var lines []string
+	for _, entry := range c.entries {
+		lines = append(lines, entry.String())
+	}
+	sort.Strings(lines)
+	data := "# Netscape HTTP Cookie File\n\n" + strings.Join(lines, "\n") + "\n"
+
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), ".cookies")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// parseCookieLine parses a line of a cookie file, which
+// may be blank or a comment, in which case it returns nil.
+func parseCookieLine(line string) (*cookieEntry, error) {
+	entry := &cookieEntry{}
+	if strings.HasPrefix(line, "#HttpOnly_") {
+		entry.httpOnly = true
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+	} else if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+		return nil, nil
+	}
+
+	fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+	if len(fields) != 7 {
+		return nil, errors.New("expected 7 tab-separated fields")
+	}
+	entry.domain = strings.TrimPrefix(fields[0], ".")
+	entry.subdomains = strings.EqualFold(fields[1], "TRUE")
+	entry.path = fields[2]
+	entry.secure = strings.EqualFold(fields[3], "TRUE")
+	expires, err := strconv.ParseInt(fields[4], 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid expiry: " + fields[4])
+	}
+	if expires > 0 {
+		entry.expires = time.Unix(expires, 0)
+	}
+	entry.name = fields[5]
+	entry.value = fields[6]
+	return entry, nil
+}
+
+// expired checks if the entry has passed its expiry time.
+// Entries without one last for the session, and are kept.
+func (c *cookieEntry) expired() bool {
+	return !c.expires.IsZero() && c.expires.Before(time.Now())
+}
+
+// equal checks if two entries would be saved as the same
+// line.
+func (c *cookieEntry) equal(other *cookieEntry) bool {
+	return c.String() == other.String()
+}
+
+func (c *cookieEntry) key() string {
+	return c.domain + "\t" + c.path + "\t" + c.name
+}
+
+func (c *cookieEntry) url() *url.URL {
+	scheme := "http"
+	if c.secure {
+		scheme = "https"
+	}
+	return &url.URL{Scheme: scheme, Host: c.domain, Path: c.path}
+}
+
+func (c *cookieEntry) cookie() *http.Cookie {
+	res := &http.Cookie{
+		Name:     c.name,
+		Value:    c.value,
+		Path:     c.path,
+		Secure:   c.secure,
+		HttpOnly: c.httpOnly,
+		Expires:  c.expires,
+	}
+	if c.subdomains {
+		res.Domain = c.domain
+	}
+	return res
+}
+
+// String formats the entry as a line of a cookie file.
+func (c *cookieEntry) String() string {
+	domain := c.domain
+	if c.subdomains {
+		domain = "." + domain
+	}
+	if c.httpOnly {
+		domain = "#HttpOnly_" + domain
+	}
+	var expires int64
+	if !c.expires.IsZero() {
+		expires = c.expires.Unix()
+	}
+	return strings.Join([]string{domain, cookieBool(c.subdomains), c.path,
+		cookieBool(c.secure), strconv.FormatInt(expires, 10), c.name, c.value}, "\t")
+}
+
+func cookieBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
@@ -0,0 +1,88 @@
+package source
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCookieFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookies_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err != nil || c.Value != "abc" {
+			http.Error(w, "subscribers only", http.StatusForbidden)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "seen", Value: "1", Path: "/"})
+		http.SetCookie(w, &http.Cookie{Name: "stale", MaxAge: -1})
+		w.Write([]byte("full text"))
+	}))
+	defer server.Close()
+	host := strings.Split(strings.TrimPrefix(server.URL, "http://"), ":")[0]
+
+	path := filepath.Join(dir, "cookies.txt")
+	contents := "# Netscape HTTP Cookie File\n\n" +
+		"#HttpOnly_" + host + "\tFALSE\t/\tFALSE\t0\tsession\tabc\n" +
+		host + "\tFALSE\t/\tFALSE\t0\tstale\tx\n" +
+		host + "\tFALSE\t/\tFALSE\t1000000000\texpired\tz\n" +
+		"example.com\tTRUE\t/\tFALSE\t0\tother\ty\n"
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldClient := HTTPClient
+	defer func() {
+		HTTPClient = oldClient
+	}()
+	if err := UseCookieFile(path); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := httpGet(nil, server.URL+"/article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# Netscape HTTP Cookie File\n\n" +
+		"#HttpOnly_" + host + "\tFALSE\t/\tFALSE\t0\tsession\tabc\n" +
+		".example.com\tTRUE\t/\tFALSE\t0\tother\ty\n" +
+		host + "\tFALSE\t/\tFALSE\t0\tseen\t1\n"
+	if string(data) != expected {
+		t.Errorf("unexpected cookie file: %q", data)
+	}
+
+	// Setting a cookie which is already saved should not
+	// rewrite the file.
+	jar, err := OpenCookieFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(path, []byte("# unchanged\n"), 0644)
+	serverURL, _ := url.Parse(server.URL)
+	jar.SetCookies(serverURL, []*http.Cookie{{Name: "seen", Value: "1", Path: "/"}})
+	if data, _ := ioutil.ReadFile(path); string(data) != "# unchanged\n" {
+		t.Errorf("cookie file rewritten without changes: %q", data)
+	}
+
+	if _, err := OpenCookieFile(filepath.Join(dir, "missing.txt")); err != nil {
+		t.Error(err)
+	}
+	ioutil.WriteFile(path, []byte("bad line\n"), 0644)
+	if _, err := OpenCookieFile(path); err == nil {
+		t.Error("expected error for malformed file")
+	}
+}
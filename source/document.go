@@ -0,0 +1,292 @@
+package source
+
+import (
+	"strings"
+
+	"github.com/yhat/scrape"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// A BlockType classifies a Block of a Document.
+type BlockType int
+
+const (
+	// Prose is ordinary running text.
+	Prose BlockType = iota
+
+	// Quote is a block quote, epigraph or pull quote.
+	Quote
+
+	// Heading is a title or section heading.
+	Heading
+
+	// Caption is a caption for an image or other figure.
+	Caption
+
+	// ListItem is one entry in a bulleted or numbered list.
+	ListItem
+)
+
+// String returns a lowercase name for the type.
+func (b BlockType) String() string {
+	switch b {
+	case Prose:
+		return "prose"
+	case Quote:
+		return "quote"
+	case Heading:
+		return "heading"
+	case Caption:
+		return "caption"
+	case ListItem:
+		return "list"
+	}
+	return "unknown"
+}
+
+// A Span is a range of byte offsets in a Block's text.
+type Span struct {
+	Start int
+	End   int
+}
+
+// A Block is a paragraph-level piece of a Document.
+type Block struct {
+	Type BlockType
+	Text string
+
+	// Emphasis lists the italic or bold ranges of Text.
+	Emphasis []Span
+}
+
+// A Document is the structured body of an Article.
+type Document struct {
+	Blocks []Block
+}
+
+// PlainDocument creates a Document of Prose blocks from a
+// plain-text body, splitting paragraphs on blank lines.
+func PlainDocument(body string) *Document {
+	res := &Document{}
+	for _, para := range strings.Split(strings.Replace(body, "\r\n", "\n", -1), "\n\n") {
+		if para = strings.TrimSpace(para); para != "" {
+			res.Blocks = append(res.Blocks, Block{Type: Prose, Text: para})
+		}
+	}
+	return res
+}
+
+// Text renders the Document as plain text, with blocks
+// separated by blank lines.
+func (d *Document) Text() string {
+	var parts []string
+	for _, b := range d.Blocks {
+		if b.Text != "" {
+			parts = append(parts, b.Text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// bodyDocument implements Article.Document for articles
+// which only have a plain-text body.
+func bodyDocument(a interface {
+	Body() (string, error)
+}) (*Document, error) {
+	body, err := a.Body()
+	if err != nil {
+		return nil, err
+	}
+	return PlainDocument(body), nil
+}
+
+// htmlDocument converts the block-level elements under an
+// HTML node to a Document.
+//
+// Code blocks, scripts and styles are dropped.
+// If there are no block-level elements, all of the text
+// becomes a single Prose block.
+func htmlDocument(root *html.Node) *Document {
+	res := &Document{}
+	htmlAddBlocks(res, root, Prose)
+	if len(res.Blocks) == 0 {
+		if block := htmlInlineBlock(root, Prose); block.Text != "" {
+			res.Blocks = append(res.Blocks, block)
+		}
+	}
+	return res
+}
+
+// paragraphDocument converts the <p> elements under an
+// HTML node to a Document, ignoring all other text.
+//
+// This is how article pages have always been read, so
+// headings and bare list items or captions are left out.
+// Paragraphs inside a block quote, caption or list item
+// are kept, but are given the type of their container.
+func paragraphDocument(root *html.Node,
+	block func(n *html.Node, blockType BlockType) Block) *Document {
+	res := &Document{}
+	for _, p := range scrape.FindAll(root, scrape.ByTag(atom.P)) {
+		blockType := Prose
+	FindContainer:
+		for n := p.Parent; n != nil && n != root; n = n.Parent {
+			switch n.DataAtom {
+			case atom.Blockquote:
+				blockType = Quote
+				break FindContainer
+			case atom.Figcaption:
+				blockType = Caption
+				break FindContainer
+			case atom.Li:
+				blockType = ListItem
+				break FindContainer
+			}
+		}
+		res.addBlock(block(p, blockType))
+	}
+	return res
+}
+
+func htmlAddBlocks(doc *Document, n *html.Node, context BlockType) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		switch child.DataAtom {
+		case atom.Script, atom.Style, atom.Pre, atom.Noscript, atom.Template:
+		case atom.P:
+			doc.addBlock(htmlInlineBlock(child, context))
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			doc.addBlock(htmlInlineBlock(child, Heading))
+		case atom.Blockquote:
+			htmlAddContainer(doc, child, Quote)
+		case atom.Figcaption:
+			htmlAddContainer(doc, child, Caption)
+		case atom.Li:
+			htmlAddContainer(doc, child, ListItem)
+		default:
+			htmlAddBlocks(doc, child, context)
+		}
+	}
+}
+
+// htmlAddContainer adds the blocks inside an element such
+// as a blockquote, which may contain paragraphs or may
+// hold its text directly.
+func htmlAddContainer(doc *Document, n *html.Node, blockType BlockType) {
+	sub := &Document{}
+	htmlAddBlocks(sub, n, blockType)
+	if len(sub.Blocks) == 0 {
+		doc.addBlock(htmlInlineBlock(n, blockType))
+	} else {
+		doc.Blocks = append(doc.Blocks, sub.Blocks...)
+	}
+}
+
+func (d *Document) addBlock(b Block) {
+	if b.Text != "" {
+		d.Blocks = append(d.Blocks, b)
+	}
+}
+
+// htmlInlineBlock creates a Block from the text inside a
+// node, collapsing whitespace in the same way as nodeText
+// and recording emphasized ranges.
+func htmlInlineBlock(n *html.Node, blockType BlockType) Block {
+	var text []byte
+	var emphasis []Span
+	space := false
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			if startsWithSpace(n.Data) {
+				space = true
+			}
+			words := strings.Fields(n.Data)
+			for _, word := range words {
+				if space && len(text) > 0 {
+					text = append(text, ' ')
+				}
+				text = append(text, word...)
+				space = true
+			}
+			space = len(words) == 0 && space || endsWithSpace(n.Data)
+			return
+		case html.ElementNode:
+			switch n.DataAtom {
+			case atom.Script, atom.Style, atom.Pre:
+				return
+			case atom.Br:
+				space = true
+				return
+			}
+		}
+		start := len(text)
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		switch n.DataAtom {
+		case atom.Em, atom.I, atom.Strong, atom.B:
+			if start < len(text) && text[start] == ' ' {
+				start++
+			}
+			if start < len(text) {
+				emphasis = append(emphasis, Span{Start: start, End: len(text)})
+			}
+		}
+	}
+	walk(n)
+
+	return Block{Type: blockType, Text: string(text), Emphasis: emphasis}
+}
+
+// scrapeTextBlock is like htmlInlineBlock, but it joins
+// the trimmed text nodes with spaces like scrape.Text, so
+// that articles read the same as they did before they had
+// Documents.
+func scrapeTextBlock(n *html.Node, blockType BlockType) Block {
+	var text []byte
+	var emphasis []Span
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			if trimmed := strings.TrimSpace(n.Data); trimmed != "" {
+				if len(text) > 0 {
+					text = append(text, ' ')
+				}
+				text = append(text, trimmed...)
+			}
+			return
+		}
+		start := len(text)
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		switch n.DataAtom {
+		case atom.Em, atom.I, atom.Strong, atom.B:
+			if start < len(text) && text[start] == ' ' {
+				start++
+			}
+			if start < len(text) {
+				emphasis = append(emphasis, Span{Start: start, End: len(text)})
+			}
+		}
+	}
+	walk(n)
+
+	return Block{Type: blockType, Text: string(text), Emphasis: emphasis}
+}
+
+func startsWithSpace(s string) bool {
+	return len(s) > 0 && strings.TrimLeft(s[:1], " \t\r\n\f") == ""
+}
+
+func endsWithSpace(s string) bool {
+	return len(s) > 0 && strings.TrimRight(s[len(s)-1:], " \t\r\n\f") == ""
+}
@@ -0,0 +1,69 @@
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHTMLDocument(t *testing.T) {
+	doc := htmlFragmentDocument(`<h2>A Title</h2>
+<p>Some <em>very</em> plain
+text, and <b>bold text</b>.</p>
+<blockquote><p>Quoted once.</p><p>Quoted twice.</p></blockquote>
+<blockquote>Bare quote</blockquote>
+<figure><img src="x.png"><figcaption>A caption</figcaption></figure>
+<ul><li>First</li><li>Second</li></ul>
+<pre>code()</pre>
+<script>var x;</script>`)
+
+	expected := []Block{
+		{Type: Heading, Text: "A Title"},
+		{
+			Type:     Prose,
+			Text:     "Some very plain text, and bold text.",
+			Emphasis: []Span{{Start: 5, End: 9}, {Start: 26, End: 35}},
+		},
+		{Type: Quote, Text: "Quoted once."},
+		{Type: Quote, Text: "Quoted twice."},
+		{Type: Quote, Text: "Bare quote"},
+		{Type: Caption, Text: "A caption"},
+		{Type: ListItem, Text: "First"},
+		{Type: ListItem, Text: "Second"},
+	}
+	if !reflect.DeepEqual(doc.Blocks, expected) {
+		t.Errorf("unexpected blocks: %+v", doc.Blocks)
+	}
+
+	text := "A Title\n\nSome very plain text, and bold text.\n\nQuoted once.\n\n" +
+		"Quoted twice.\n\nBare quote\n\nA caption\n\nFirst\n\nSecond"
+	if doc.Text() != text {
+		t.Errorf("unexpected text: %q", doc.Text())
+	}
+}
+
+func TestMarkdownDocument(t *testing.T) {
+	doc := markdownDocument("# Title\n\nSome *plain*\ntext.\n\n> A quote\n> continued.\n\n" +
+		"- One\n- Two\n\n    code\n")
+
+	var types []BlockType
+	for _, b := range doc.Blocks {
+		types = append(types, b.Type)
+	}
+	if !reflect.DeepEqual(types, []BlockType{Heading, Prose, Quote, ListItem, ListItem}) {
+		t.Errorf("unexpected block types: %v", types)
+	}
+	if text := doc.Text(); text != "Title\n\nSome plain text.\n\nA quote continued.\n\nOne\n\nTwo" {
+		t.Errorf("unexpected text: %q", text)
+	}
+}
+
+func TestPlainDocument(t *testing.T) {
+	doc := PlainDocument("First paragraph.\r\n\r\n\r\nSecond\nparagraph.\n")
+	expected := []Block{
+		{Type: Prose, Text: "First paragraph."},
+		{Type: Prose, Text: "Second\nparagraph."},
+	}
+	if !reflect.DeepEqual(doc.Blocks, expected) {
+		t.Errorf("unexpected blocks: %+v", doc.Blocks)
+	}
+}
@@ -0,0 +1,412 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Email is a Source that reads messages from local mbox
+// files and Maildir (or Enron-style) directories.
+//
+// Messages are grouped by sender address, and only the
+// sender's own text is kept: quoted replies, attribution
+// lines, forwarded messages and signatures are removed.
+type Email struct{}
+
+// Help returns the usage information for this Source.
+func (_ Email) Help() string {
+	return "Read messages from mbox files and Maildir directories.\n" +
+		"Environment variables:\n" +
+		" EMAIL_PATHS   comma-separated mbox files or directories to\n" +
+		"               search for message files (required)"
+}
+
+// Authors indexes every message and then produces one
+// author per sender address.
+func (_ Email) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+	authChan := make(chan Author, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(authChan)
+		defer close(errChan)
+
+		paths := os.Getenv("EMAIL_PATHS")
+		if paths == "" {
+			errChan <- errors.New("missing EMAIL_PATHS")
+			return
+		}
+
+		index := map[string][]emailLocation{}
+		for _, path := range strings.Split(paths, ",") {
+			if err := emailIndexPath(strings.TrimSpace(path), index); err != nil {
+				errChan <- err
+				return
+			}
+		}
+
+		var addresses []string
+		for addr := range index {
+			addresses = append(addresses, addr)
+		}
+		sort.Strings(addresses)
+
+		for _, addr := range addresses {
+			select {
+			case <-stop:
+				return
+			case authChan <- &emailAuthor{address: addr, messages: index[addr]}:
+			}
+		}
+	}()
+
+	return authChan, errChan
+}
+
+// An emailLocation identifies a message within a file.
+// For files containing a single message, length is -1.
+type emailLocation struct {
+	path   string
+	offset int64
+	length int64
+}
+
+func (e emailLocation) read() ([]byte, error) {
+	if e.length < 0 {
+		return ioutil.ReadFile(e.path)
+	}
+	f, err := os.Open(e.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	res := make([]byte, e.length)
+	if _, err := f.ReadAt(res, e.offset); err != nil {
+		return nil, err
+	}
+	return emailUnescapeMbox(res), nil
+}
+
+func emailIndexPath(path string, index map[string][]emailLocation) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return emailIndexMbox(path, index)
+	}
+	return filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return err
+		}
+		loc := emailLocation{path: path, length: -1}
+		data, err := loc.read()
+		if err != nil {
+			return err
+		}
+		emailAddToIndex(data, loc, index)
+		return nil
+	})
+}
+
+// emailIndexMbox records the location of every message in
+// an mbox file, where each message begins with a "From "
+// line.
+func emailIndexMbox(path string, index map[string][]emailLocation) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var offset int64
+	var current *emailLocation
+	var message bytes.Buffer
+	flush := func() {
+		if current != nil {
+			current.length = offset - current.offset
+			emailAddToIndex(emailUnescapeMbox(message.Bytes()), *current, index)
+		}
+		message.Reset()
+	}
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if bytes.HasPrefix(line, []byte("From ")) {
+				flush()
+				offset += int64(len(line))
+				current = &emailLocation{path: path, offset: offset}
+				continue
+			}
+			message.Write(line)
+			offset += int64(len(line))
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	flush()
+	return nil
+}
+
+func emailAddToIndex(data []byte, loc emailLocation, index map[string][]emailLocation) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	addr, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return
+	}
+	normalized := strings.ToLower(addr.Address)
+	index[normalized] = append(index[normalized], loc)
+}
+
+var emailEscapedFrom = regexp.MustCompile(`(?m)^>(>*From )`)
+
+// emailUnescapeMbox undoes the ">From " quoting applied to
+// message bodies in mbox files.
+func emailUnescapeMbox(data []byte) []byte {
+	return emailEscapedFrom.ReplaceAll(data, []byte("$1"))
+}
+
+type emailAuthor struct {
+	address  string
+	messages []emailLocation
+}
+
+func (e *emailAuthor) Name() string {
+	return e.address
+}
+
+func (e *emailAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan error) {
+	artChan := make(chan Article, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(artChan)
+		defer close(errChan)
+		for _, loc := range e.messages {
+			select {
+			case <-stop:
+				return
+			case artChan <- &emailMessage{loc: loc, address: e.address}:
+			}
+		}
+	}()
+
+	return artChan, errChan
+}
+
+type emailMessage struct {
+	loc     emailLocation
+	address string
+}
+
+func (e *emailMessage) ID() string {
+	msg, err := e.message()
+	if err == nil {
+		if id := msg.Header.Get("Message-Id"); id != "" {
+			return hashID(id)
+		}
+	}
+	return hashID(e.loc.path + ":" + strconv.FormatInt(e.loc.offset, 10))
+}
+
+// Body decodes the first text/plain part of the message
+// and strips everything not written by the sender.
+func (e *emailMessage) Body() (string, error) {
+	msg, err := e.message()
+	if err != nil {
+		return "", err
+	}
+	text, ok, err := emailPlainText(msg.Header, msg.Body)
+	if err != nil {
+		return "", err
+	} else if !ok {
+		return "", errors.New("no text/plain part")
+	}
+	body := emailStripReplies(text)
+	if body == "" {
+		return "", errors.New("no original text in message")
+	}
+	return body, nil
+}
+
+func (e *emailMessage) Document() (*Document, error) {
+	return bodyDocument(e)
+}
+
+func (e *emailMessage) Authors() ([]string, error) {
+	return []string{e.address}, nil
+}
+
+func (e *emailMessage) Date() (time.Time, error) {
+	msg, err := e.message()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if msg.Header.Get("Date") == "" {
+		return time.Time{}, nil
+	}
+	// Malformed dates are common in old archives.
+	date, _ := msg.Header.Date()
+	return date, nil
+}
+
+func (e *emailMessage) message() (*mail.Message, error) {
+	data, err := e.loc.read()
+	if err != nil {
+		return nil, err
+	}
+	return mail.ReadMessage(bytes.NewReader(data))
+}
+
+// emailPlainText finds the first text/plain entity in a
+// (possibly multipart) message body and decodes it.
+func emailPlainText(header map[string][]string, body io.Reader) (string, bool, error) {
+	get := func(key string) string {
+		if v := header[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	mediaType, params, err := mime.ParseMediaType(get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		r := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := r.NextPart()
+			if err == io.EOF {
+				return "", false, nil
+			} else if err != nil {
+				return "", false, err
+			}
+			text, ok, err := emailPlainText(part.Header, part)
+			if err != nil || ok {
+				return text, ok, err
+			}
+		}
+	} else if mediaType != "text/plain" {
+		return "", false, nil
+	}
+
+	switch strings.ToLower(get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		// multipart.Part decodes this automatically and
+		// removes the header, so we only see it at the top
+		// level.
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, emailBase64Cleaner{body})
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", false, err
+	}
+
+	switch charset := strings.ToLower(params["charset"]); charset {
+	case "", "utf-8", "utf8", "us-ascii":
+	case "iso-8859-1", "latin1":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes), true, nil
+	case "windows-1252", "cp1252":
+		// Unlike Latin-1, this uses 0x80-0x9f for curly
+		// quotes, dashes and the like.
+		decoded, err := charmap.Windows1252.NewDecoder().Bytes(data)
+		if err != nil {
+			return "", false, err
+		}
+		return string(decoded), true, nil
+	default:
+		log.Println("unknown email charset " + charset + ", reading as UTF-8")
+	}
+	return string(data), true, nil
+}
+
+// emailBase64Cleaner drops the line breaks inside base64
+// encoded bodies.
+type emailBase64Cleaner struct {
+	r io.Reader
+}
+
+func (e emailBase64Cleaner) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	j := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			p[j] = b
+			j++
+		}
+	}
+	return j, err
+}
+
+var (
+	emailAttribution = regexp.MustCompile(`^On .*wrote:\s*$`)
+	emailCutoff      = regexp.MustCompile(`^(-+ ?Original Message ?-+|-+ ?Forwarded by .*|` +
+		`Begin forwarded message:|_{10,})\s*$`)
+)
+
+// emailStripReplies removes quoted lines, attribution
+// lines, forwarded or replied-to messages and signatures.
+func emailStripReplies(text string) string {
+	lines := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	var kept []string
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if line == "-- " || trimmed == "--" || emailCutoff.MatchString(trimmed) {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") || emailAttribution.MatchString(trimmed) {
+			continue
+		}
+		// Attributions are often wrapped onto a second line.
+		if strings.HasSuffix(trimmed, "wrote:") && i > 0 &&
+			strings.HasPrefix(strings.TrimSpace(lines[i-1]), "On ") {
+			if len(kept) > 0 {
+				kept = kept[:len(kept)-1]
+			}
+			continue
+		}
+		kept = append(kept, strings.TrimRight(line, " \t"))
+	}
+
+	// Collapse runs of blank lines left behind.
+	var res []string
+	for _, line := range kept {
+		if line == "" && (len(res) == 0 || res[len(res)-1] == "") {
+			continue
+		}
+		res = append(res, line)
+	}
+	return strings.TrimSpace(strings.Join(res, "\n"))
+}
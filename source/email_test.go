@@ -0,0 +1,76 @@
+package source
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmail(t *testing.T) {
+	dir := filepath.Join("testdata", "email")
+	setEnv(t, map[string]string{
+		"EMAIL_PATHS": filepath.Join(dir, "list.mbox") + "," + filepath.Join(dir, "maildir"),
+	})
+
+	authors := collectAuthors(t, Email{})
+	if len(authors) != 2 || authors[0].Name() != "jane@example.com" ||
+		authors[1].Name() != "john@example.com" {
+		t.Fatal("unexpected authors:", authors)
+	}
+
+	expected := map[string][]string{
+		"jane@example.com": {
+			"I think we should ship on Friday.\nFrom what I can tell, nothing is blocking us.",
+			"Please send me your budget numbers by Thursday.\n\nThanks",
+		},
+		"john@example.com": {
+			"Friday works for me. The café will be open to celebrate, " +
+				"and this line is soft-wrapped.",
+			"Here are the numbers.",
+		},
+	}
+	for _, author := range authors {
+		arts := collectArticles(t, author)
+		bodies := expected[author.Name()]
+		if len(arts) != len(bodies) {
+			t.Fatalf("%s: expected %d messages but got %d", author.Name(),
+				len(bodies), len(arts))
+		}
+		for i, art := range arts {
+			body, err := art.Body()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body != bodies[i] {
+				t.Errorf("%s message %d: unexpected body %q", author.Name(), i, body)
+			}
+			if date, err := art.Date(); err != nil || date.Year() != 2016 {
+				t.Errorf("%s message %d: unexpected date %v (%v)", author.Name(), i,
+					date, err)
+			}
+		}
+	}
+}
+
+func TestEmailCharsets(t *testing.T) {
+	tests := []struct {
+		contentType string
+		data        string
+		expected    string
+	}{
+		{"text/plain; charset=utf-8", "caf\xc3\xa9", "café"},
+		{"text/plain", "caf\xc3\xa9", "café"},
+		{"text/plain; charset=ISO-8859-1", "caf\xe9", "café"},
+		{"text/plain; charset=windows-1252", "\x93caf\xe9\x94 \x96 open", "\u201ccafé\u201d \u2013 open"},
+		{"text/plain; charset=x-unknown", "caf\xc3\xa9", "café"},
+	}
+	for _, test := range tests {
+		header := map[string][]string{"Content-Type": {test.contentType}}
+		text, ok, err := emailPlainText(header, strings.NewReader(test.data))
+		if err != nil || !ok {
+			t.Errorf("%s: unexpected result (%v, %v)", test.contentType, ok, err)
+		} else if text != test.expected {
+			t.Errorf("%s: expected %q but got %q", test.contentType, test.expected, text)
+		}
+	}
+}
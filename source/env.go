@@ -0,0 +1,77 @@
+package source
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envInt reads an integer from an environment variable,
+// returning def if the variable is unset.
+func envInt(name string, def int) (int, error) {
+	s := os.Getenv(name)
+	if s == "" {
+		return def, nil
+	}
+	res, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.New("invalid " + name + ": " + s)
+	}
+	return res, nil
+}
+
+// globList expands an environment variable containing a
+// comma-separated list of paths or glob patterns.
+// It fails if the variable is unset or if any pattern
+// matches nothing.
+func globList(name string) ([]string, error) {
+	env := os.Getenv(name)
+	if env == "" {
+		return nil, errors.New("missing " + name)
+	}
+	var res []string
+	for _, pattern := range strings.Split(env, ",") {
+		matches, err := filepath.Glob(strings.TrimSpace(pattern))
+		if err != nil {
+			return nil, err
+		} else if len(matches) == 0 {
+			return nil, errors.New("no files match: " + pattern)
+		}
+		res = append(res, matches...)
+	}
+	return res, nil
+}
+
+// envList reads a comma-separated list from an environment
+// variable, returning def if the variable is unset.
+// Entries are trimmed and lowercased.
+func envList(name string, def []string) []string {
+	s, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+	var res []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+// envDate reads a date from an environment variable,
+// returning the zero time if the variable is unset.
+func envDate(name string) (time.Time, error) {
+	s := os.Getenv(name)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	res := parseDate(s)
+	if res.IsZero() {
+		return res, errors.New("invalid " + name + ": " + s)
+	}
+	return res, nil
+}
@@ -0,0 +1,255 @@
+package source
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/yhat/scrape"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// An extractedPage stores the metadata and body text
+// extracted from a generic article page.
+type extractedPage struct {
+	Authors  []string
+	Date     time.Time
+	Headline string
+	Body     string
+}
+
+// extractPage reads schema.org JSON-LD, OpenGraph and
+// other meta tags from a page, falling back on a
+// readability-style heuristic to find the body text.
+func extractPage(page *html.Node) *extractedPage {
+	res := &extractedPage{}
+	if ld := findJSONLDArticle(page); ld != nil {
+		res.Authors = ld.authorNames()
+		res.Date = parseDate(ld.DatePublished)
+		res.Headline = ld.Headline
+		res.Body = strings.TrimSpace(ld.ArticleBody)
+	}
+
+	meta := func(attr, value string) []string {
+		var res []string
+		for _, n := range scrape.FindAll(page, func(n *html.Node) bool {
+			return n.DataAtom == atom.Meta && scrape.Attr(n, attr) == value
+		}) {
+			if content := strings.TrimSpace(scrape.Attr(n, "content")); content != "" {
+				res = append(res, content)
+			}
+		}
+		return res
+	}
+	if len(res.Authors) == 0 {
+		for _, name := range append(meta("name", "author"), meta("property", "article:author")...) {
+			// article:author is often a profile URL rather
+			// than a name.
+			if !strings.Contains(name, "://") {
+				res.Authors = append(res.Authors, normalizeByline(name))
+			}
+		}
+		res.Authors = uniqueStrings(res.Authors)
+	}
+	if res.Date.IsZero() {
+		if dates := meta("property", "article:published_time"); len(dates) > 0 {
+			res.Date = parseDate(dates[0])
+		}
+	}
+	if res.Headline == "" {
+		if titles := meta("property", "og:title"); len(titles) > 0 {
+			res.Headline = titles[0]
+		}
+	}
+	if res.Body == "" {
+		res.Body = readabilityText(page)
+	}
+	return res
+}
+
+type jsonLDArticle struct {
+	Type          interface{}       `json:"@type"`
+	Graph         []json.RawMessage `json:"@graph"`
+	Author        json.RawMessage   `json:"author"`
+	DatePublished string            `json:"datePublished"`
+	Headline      string            `json:"headline"`
+	ArticleBody   string            `json:"articleBody"`
+}
+
+func (j *jsonLDArticle) isArticle() bool {
+	var types []interface{}
+	switch t := j.Type.(type) {
+	case string:
+		types = []interface{}{t}
+	case []interface{}:
+		types = t
+	}
+	for _, t := range types {
+		if s, ok := t.(string); ok && (strings.HasSuffix(s, "Article") || s == "BlogPosting") {
+			return true
+		}
+	}
+	return false
+}
+
+// authorNames decodes the author field, which may be a
+// string, a Person object, or a list of either.
+func (j *jsonLDArticle) authorNames() []string {
+	var list []json.RawMessage
+	if err := json.Unmarshal(j.Author, &list); err != nil {
+		list = []json.RawMessage{j.Author}
+	}
+	var res []string
+	for _, raw := range list {
+		var name string
+		if err := json.Unmarshal(raw, &name); err != nil {
+			var person struct {
+				Name string `json:"name"`
+			}
+			json.Unmarshal(raw, &person)
+			name = person.Name
+		}
+		if name = normalizeByline(name); name != "" {
+			res = append(res, name)
+		}
+	}
+	return uniqueStrings(res)
+}
+
+func findJSONLDArticle(page *html.Node) *jsonLDArticle {
+	scripts := scrape.FindAll(page, func(n *html.Node) bool {
+		return n.DataAtom == atom.Script && scrape.Attr(n, "type") == "application/ld+json"
+	})
+	for _, script := range scripts {
+		if script.FirstChild == nil {
+			continue
+		}
+		if res := findJSONLDArticleIn([]byte(script.FirstChild.Data)); res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+func findJSONLDArticleIn(data []byte) *jsonLDArticle {
+	var list []json.RawMessage
+	if err := json.Unmarshal(data, &list); err != nil {
+		list = []json.RawMessage{data}
+	}
+	for _, item := range list {
+		var obj jsonLDArticle
+		if err := json.Unmarshal(item, &obj); err != nil {
+			continue
+		}
+		if obj.isArticle() {
+			return &obj
+		}
+		for _, sub := range obj.Graph {
+			if res := findJSONLDArticleIn(sub); res != nil {
+				return res
+			}
+		}
+	}
+	return nil
+}
+
+// readabilityText finds the element whose paragraphs hold
+// the most prose and returns the text of those paragraphs.
+func readabilityText(page *html.Node) string {
+	removeNodes(page, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Script, atom.Style, atom.Nav, atom.Aside, atom.Footer, atom.Header,
+			atom.Form, atom.Figure, atom.Noscript, atom.Iframe:
+			return true
+		}
+		return false
+	})
+
+	scores := map[*html.Node]float64{}
+	var best *html.Node
+	for _, p := range scrape.FindAll(page, scrape.ByTag(atom.P)) {
+		text := nodeText(p)
+		if len(text) < 25 || p.Parent == nil {
+			continue
+		}
+		score := 1 + float64(strings.Count(text, ",")) + float64(len(text))/100
+		scores[p.Parent] += score
+		if p.Parent.Parent != nil {
+			scores[p.Parent.Parent] += score / 2
+		}
+	}
+	for n, score := range scores {
+		if best == nil || score > scores[best] {
+			best = n
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return joinParagraphs(scrape.FindAll(best, scrape.ByTag(atom.P)))
+}
+
+// normalizeByline cleans up an author name from a byline.
+func normalizeByline(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if strings.HasPrefix(strings.ToLower(name), "by ") {
+		name = name[3:]
+	}
+	return name
+}
+
+// splitByline splits a byline such as "By Jane Doe, John
+// Roe and Ann Poe" into normalized names.
+func splitByline(byline string) []string {
+	byline = normalizeByline(byline)
+	byline = strings.Replace(byline, " & ", ", ", -1)
+	byline = strings.Replace(byline, " and ", ", ", -1)
+	var res []string
+	for _, name := range strings.Split(byline, ",") {
+		name = normalizeByline(name)
+		if strings.HasPrefix(name, "and ") {
+			name = name[4:]
+		}
+		if name != "" {
+			res = append(res, name)
+		}
+	}
+	return uniqueStrings(res)
+}
+
+func containsString(list []string, x string) bool {
+	for _, y := range list {
+		if y == x {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueStrings(list []string) []string {
+	seen := map[string]bool{}
+	var res []string
+	for _, x := range list {
+		if !seen[x] {
+			seen[x] = true
+			res = append(res, x)
+		}
+	}
+	return res
+}
+
+// parseDate parses the date formats commonly found in page
+// metadata, returning the zero time on failure.
+func parseDate(s string) time.Time {
+	layouts := []string{time.RFC3339, "2006-01-02T15:04:05Z0700", "2006-01-02T15:04:05",
+		"2006-01-02T15:04Z07:00", "2006-01-02 15:04:05 -0700", "2006-01-02 15:04:05",
+		"2006-01-02 15:04", "2006-01-02", time.RFC1123Z, time.RFC1123}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,409 @@
+package source
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// Feed is a Source that reads entries from RSS and Atom
+// feeds, grouping them by author.
+//
+// Entries with full content are used as-is, while entries
+// with only a summary are scraped from their linked page.
+// Paged and archived feeds (RFC 5005) are followed through
+// their "next" and "prev-archive" links.
+type Feed struct {
+	// client makes the requests for the source, or is
+	// nil to use HTTPClient.
+	client *http.Client
+}
+
+// Help returns the usage information for this Source.
+func (_ Feed) Help() string {
+	return "Read entries from RSS and Atom feeds.\n" +
+		"Environment variables:\n" +
+		" FEED_URLS   comma-separated feed URLs or local paths (required)"
+}
+
+// withClient returns a copy of the source which makes its
+// requests with client.
+func (f Feed) withClient(client *http.Client) Source {
+	f.client = client
+	return f
+}
+
+// Authors reads every feed (including archive pages) and
+// then produces the authors in alphabetical order.
+func (f Feed) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+	authChan := make(chan Author, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(authChan)
+		defer close(errChan)
+
+		env := os.Getenv("FEED_URLS")
+		if env == "" {
+			errChan <- errors.New("missing FEED_URLS")
+			return
+		}
+
+		authors := map[string]*feedAuthor{}
+		seen := map[string]bool{}
+		var queue []string
+		for _, u := range strings.Split(env, ",") {
+			queue = append(queue, strings.TrimSpace(u))
+		}
+		for len(queue) > 0 {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			location := queue[0]
+			queue = queue[1:]
+			if seen[location] {
+				continue
+			}
+			seen[location] = true
+
+			entries, links, err := feedRead(f.client, location)
+			if err != nil {
+				errChan <- errors.New(location + ": " + err.Error())
+				return
+			}
+			queue = append(queue, links...)
+			for _, entry := range entries {
+				entry.client = f.client
+				entry.authors = uniqueStrings(entry.authors)
+				for _, name := range entry.authors {
+					if authors[name] == nil {
+						authors[name] = &feedAuthor{name: name}
+					}
+					authors[name].entries = append(authors[name].entries, entry)
+				}
+			}
+		}
+
+		var names []string
+		for name := range authors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			select {
+			case <-stop:
+				return
+			case authChan <- authors[name]:
+			}
+		}
+	}()
+
+	return authChan, errChan
+}
+
+type feedDocument struct {
+	XMLName xml.Name
+
+	// RSS 2.0
+	Channel struct {
+		Links []feedAtomLink `xml:"http://www.w3.org/2005/Atom link"`
+		Items []struct {
+			Title    string   `xml:"title"`
+			Link     string   `xml:"link"`
+			GUID     string   `xml:"guid"`
+			Author   string   `xml:"author"`
+			Creators []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+			PubDate  string   `xml:"pubDate"`
+			Encoded  string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+		} `xml:"item"`
+	} `xml:"channel"`
+
+	// Atom
+	Links   []feedAtomLink   `xml:"http://www.w3.org/2005/Atom link"`
+	Authors []feedAtomPerson `xml:"http://www.w3.org/2005/Atom author"`
+	Entries []struct {
+		ID        string           `xml:"id"`
+		Title     string           `xml:"title"`
+		Links     []feedAtomLink   `xml:"link"`
+		Authors   []feedAtomPerson `xml:"author"`
+		Published string           `xml:"published"`
+		Updated   string           `xml:"updated"`
+		Content   struct {
+			Type  string `xml:"type,attr"`
+			Inner string `xml:",innerxml"`
+		} `xml:"content"`
+	} `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+type feedAtomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type feedAtomPerson struct {
+	Name string `xml:"name"`
+}
+
+// feedRead reads the entries in a feed document, along
+// with links to further pages of the feed.
+func feedRead(client *http.Client, location string) ([]*feedEntry, []string, error) {
+	var r io.ReadCloser
+	if strings.Contains(location, "://") {
+		resp, err := httpGet(client, location)
+		if err != nil {
+			return nil, nil, err
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(location)
+		if err != nil {
+			return nil, nil, err
+		}
+		r = f
+	}
+	defer r.Close()
+
+	var doc feedDocument
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.CharsetReader = charset.NewReaderLabel
+	if err := dec.Decode(&doc); err != nil {
+		return nil, nil, err
+	}
+
+	var entries []*feedEntry
+	var links []string
+	addLinks := func(list []feedAtomLink) {
+		for _, l := range list {
+			if l.Rel == "next" || l.Rel == "prev-archive" {
+				links = append(links, feedResolve(location, l.Href))
+			}
+		}
+	}
+
+	switch doc.XMLName.Local {
+	case "rss":
+		addLinks(doc.Channel.Links)
+		for _, item := range doc.Channel.Items {
+			entry := &feedEntry{
+				id:      item.GUID,
+				link:    feedResolve(location, strings.TrimSpace(item.Link)),
+				date:    parseDate(item.PubDate),
+				content: htmlFragmentDocument(item.Encoded),
+			}
+			if entry.id == "" {
+				entry.id = entry.link
+			}
+			if entry.id == "" {
+				entry.id = feedFallbackID(item.Title, entry.date, item.Encoded)
+			}
+			for _, c := range item.Creators {
+				entry.authors = append(entry.authors, feedNormalizeAuthor(c))
+			}
+			if len(entry.authors) == 0 && item.Author != "" {
+				entry.authors = []string{feedNormalizeAuthor(item.Author)}
+			}
+			entries = append(entries, entry)
+		}
+	case "feed":
+		addLinks(doc.Links)
+		for _, item := range doc.Entries {
+			entry := &feedEntry{id: item.ID, date: parseDate(item.Published)}
+			if entry.date.IsZero() {
+				entry.date = parseDate(item.Updated)
+			}
+			for _, l := range item.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					entry.link = feedResolve(location, l.Href)
+					break
+				}
+			}
+			entry.content = feedAtomContent(item.Content.Type, item.Content.Inner)
+			people := item.Authors
+			if len(people) == 0 {
+				people = doc.Authors
+			}
+			for _, p := range people {
+				entry.authors = append(entry.authors, feedNormalizeAuthor(p.Name))
+			}
+			if entry.id == "" {
+				entry.id = entry.link
+			}
+			if entry.id == "" {
+				entry.id = feedFallbackID(item.Title, entry.date, item.Content.Inner)
+			}
+			entries = append(entries, entry)
+		}
+	default:
+		return nil, nil, errors.New("unknown feed type: " + doc.XMLName.Local)
+	}
+
+	return entries, links, nil
+}
+
+// feedFallbackID identifies an entry which has neither an
+// ID nor a link by its title, date and content.
+func feedFallbackID(title string, date time.Time, content string) string {
+	return strings.Join([]string{title, date.Format(time.RFC3339), content}, "\x00")
+}
+
+// feedResolve resolves a link in the feed at location.
+// Relative links in local feeds are paths relative to the
+// feed file's directory.
+func feedResolve(location, link string) string {
+	if link == "" {
+		return link
+	} else if strings.Contains(location, "://") {
+		return resolveURL(location, link)
+	} else if strings.Contains(link, "://") || filepath.IsAbs(link) {
+		return link
+	}
+	return filepath.Join(filepath.Dir(location), link)
+}
+
+// feedAtomContent converts the raw XML inside an Atom
+// content element to a Document.
+func feedAtomContent(contentType, inner string) *Document {
+	switch contentType {
+	case "", "text":
+		return PlainDocument(feedUnescape(inner))
+	case "html":
+		return htmlFragmentDocument(feedUnescape(inner))
+	case "xhtml":
+		return htmlFragmentDocument(inner)
+	}
+	return &Document{}
+}
+
+// feedUnescape decodes the character data in an XML
+// fragment.
+func feedUnescape(inner string) string {
+	dec := xml.NewDecoder(strings.NewReader("<x>" + inner + "</x>"))
+	var s string
+	if err := dec.Decode(&s); err != nil {
+		return ""
+	}
+	return s
+}
+
+var feedEmailName = regexp.MustCompile(`^\S+@\S+\s+\((.*)\)$`)
+
+// feedNormalizeAuthor turns forms like "jane@x.com (Jane
+// Doe)" and "Jane Doe <jane@x.com>" into "Jane Doe".
+func feedNormalizeAuthor(name string) string {
+	name = strings.TrimSpace(name)
+	if match := feedEmailName.FindStringSubmatch(name); match != nil {
+		name = match[1]
+	}
+	if i := strings.Index(name, "<"); i > 0 {
+		name = name[:i]
+	}
+	return normalizeByline(name)
+}
+
+type feedAuthor struct {
+	name    string
+	entries []*feedEntry
+}
+
+func (f *feedAuthor) Name() string {
+	return f.name
+}
+
+func (f *feedAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan error) {
+	artChan := make(chan Article, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(artChan)
+		defer close(errChan)
+		for _, e := range f.entries {
+			select {
+			case <-stop:
+				return
+			case artChan <- e:
+			}
+		}
+	}()
+
+	return artChan, errChan
+}
+
+type feedEntry struct {
+	id      string
+	link    string
+	authors []string
+	date    time.Time
+	content *Document
+	client  *http.Client
+}
+
+func (f *feedEntry) ID() string {
+	return hashID(f.id)
+}
+
+func (f *feedEntry) URL() string {
+	return f.link
+}
+
+func (f *feedEntry) Body() (string, error) {
+	doc, err := f.Document()
+	if err != nil {
+		return "", err
+	}
+	return doc.Text(), nil
+}
+
+func (f *feedEntry) Document() (*Document, error) {
+	if f.content != nil && len(f.content.Blocks) > 0 {
+		return f.content, nil
+	}
+	if f.link == "" {
+		return nil, errors.New("entry has no content or link")
+	}
+	var page *html.Node
+	var err error
+	if strings.Contains(f.link, "://") {
+		page, err = getHTML(f.client, f.link)
+	} else {
+		page, err = feedReadPage(f.link)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if body := extractPage(page).Body; body != "" {
+		return PlainDocument(body), nil
+	}
+	return nil, errors.New("no body text found")
+}
+
+// feedReadPage parses the page linked by an entry in a
+// local feed.
+func feedReadPage(path string) (*html.Node, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return html.Parse(f)
+}
+
+func (f *feedEntry) Date() (time.Time, error) {
+	return f.date, nil
+}
+
+func (f *feedEntry) Authors() ([]string, error) {
+	return f.authors, nil
+}
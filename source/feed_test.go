@@ -0,0 +1,128 @@
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestFeed(t *testing.T) {
+	server := httptest.NewServer(nil)
+	defer server.Close()
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveFixture(w, "feed"+r.URL.Path, server.URL)
+	})
+
+	setEnv(t, map[string]string{
+		"FEED_URLS": server.URL + "/rss.xml, " + server.URL + "/atom.xml",
+	})
+
+	authors := collectAuthors(t, Feed{})
+	if len(authors) != 2 || authors[0].Name() != "Jane Doe" ||
+		authors[1].Name() != "John Roe" {
+		t.Fatal("unexpected authors:", authors)
+	}
+
+	expected := [][]string{
+		{
+			"The full text of a post.\n\nAnother paragraph.",
+			"Joint work.",
+			"An escaped HTML issue.",
+			"An XHTML issue.",
+		},
+		{
+			"The linked page holds the full text, with enough words, commas, and clauses.\n\n" +
+				"It has a second paragraph as well, which is also reasonably long.",
+			"Joint work.",
+		},
+	}
+	expectedDays := [][]int{{14, 1, 20, 13}, {15, 1}}
+	for i, author := range authors {
+		arts := collectArticles(t, author)
+		if len(arts) != len(expected[i]) {
+			t.Fatalf("author %d: expected %d articles but got %d", i,
+				len(expected[i]), len(arts))
+		}
+		for j, art := range arts {
+			body, err := art.Body()
+			if err != nil {
+				t.Errorf("author %d article %d: %v", i, j, err)
+			} else if body != expected[i][j] {
+				t.Errorf("author %d article %d: unexpected body %q", i, j, body)
+			}
+			if date, _ := art.Date(); date.Day() != expectedDays[i][j] {
+				t.Errorf("author %d article %d: unexpected date %v", i, j, date)
+			}
+			names, _ := art.Authors()
+			if (body == "Joint work.") != (len(names) == 2) {
+				t.Errorf("author %d article %d: unexpected authors %v", i, j, names)
+			}
+		}
+	}
+
+	for input, expected := range map[string]string{
+		"jane@example.com (Jane Doe)": "Jane Doe",
+		"Jane Doe <jane@example.com>": "Jane Doe",
+		" by  Jane\nDoe ":             "Jane Doe",
+	} {
+		if actual := feedNormalizeAuthor(input); actual != expected {
+			t.Errorf("normalize %q: got %q", input, actual)
+		}
+	}
+}
+
+func TestFeedLocal(t *testing.T) {
+	// The links in these feeds are relative to their
+	// directory rather than the working directory.
+	setEnv(t, map[string]string{
+		"FEED_URLS": filepath.Join("testdata", "feed", "local", "latin1.xml"),
+	})
+
+	authors := collectAuthors(t, Feed{})
+	if len(authors) != 1 || authors[0].Name() != "Jane Doe" {
+		t.Fatal("unexpected authors:", authors)
+	}
+	expected := []string{
+		"Café au lait.",
+		"The linked page holds the full text, with enough words, commas, and clauses.\n\n" +
+			"It has a second paragraph as well, which is also reasonably long.",
+	}
+	arts := collectArticles(t, authors[0])
+	if len(arts) != len(expected) {
+		t.Fatalf("expected %d articles but got %d", len(expected), len(arts))
+	}
+	for i, art := range arts {
+		body, err := art.Body()
+		if err != nil {
+			t.Errorf("article %d: %v", i, err)
+		} else if body != expected[i] {
+			t.Errorf("article %d: unexpected body %q", i, body)
+		}
+	}
+}
+
+func TestFeedUntagged(t *testing.T) {
+	// Entries with neither a GUID nor a link must still get
+	// distinct IDs which are the same every time.
+	path := filepath.Join("testdata", "feed", "local", "untagged.xml")
+	entries, _, err := feedRead(nil, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _, err := feedRead(nil, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || len(again) != 2 {
+		t.Fatal("unexpected number of entries")
+	}
+	if entries[0].ID() == entries[1].ID() {
+		t.Error("entries share an ID")
+	}
+	for i, e := range entries {
+		if again[i].ID() != e.ID() {
+			t.Errorf("entry %d: ID changed from %s to %s", i, e.ID(), again[i].ID())
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Git is a Source that reads commit messages (and
+// optionally Markdown documents) from local git
+// repositories.
+//
+// Authors are identified by their email address after
+// applying the repository's .mailmap.
+type Git struct{}
+
+// Help returns the usage information for this Source.
+func (_ Git) Help() string {
+	return "Read commit messages from local git repositories.\n" +
+		"Environment variables:\n" +
+		" GIT_REPOS        comma-separated repository paths (required)\n" +
+		" GIT_DOCS         set to 1 to include Markdown files that were\n" +
+		"                  only ever committed by one author\n" +
+		" GIT_MIN_LENGTH   minimum commit message length (default 1)"
+}
+
+// Authors reads the history of every repository and
+// produces one author per (mailmapped) email address.
+func (_ Git) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+	authChan := make(chan Author, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(authChan)
+		defer close(errChan)
+
+		repos := os.Getenv("GIT_REPOS")
+		if repos == "" {
+			errChan <- errors.New("missing GIT_REPOS")
+			return
+		}
+		minLength, err := envInt("GIT_MIN_LENGTH", 1)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		authors := map[string]*gitAuthor{}
+		for _, repo := range strings.Split(repos, ",") {
+			repo = strings.TrimSpace(repo)
+			if err := gitIndexCommits(repo, minLength, authors); err != nil {
+				errChan <- err
+				return
+			}
+			if os.Getenv("GIT_DOCS") == "1" {
+				if err := gitIndexDocs(repo, authors); err != nil {
+					errChan <- err
+					return
+				}
+			}
+		}
+
+		var emails []string
+		for email := range authors {
+			emails = append(emails, email)
+		}
+		sort.Strings(emails)
+
+		for _, email := range emails {
+			select {
+			case <-stop:
+				return
+			case authChan <- authors[email]:
+			}
+		}
+	}()
+
+	return authChan, errChan
+}
+
+// gitIndexCommits records every non-merge commit in a
+// repository under its author.
+func gitIndexCommits(repo string, minLength int, authors map[string]*gitAuthor) error {
+	out, err := gitCommand(repo, "log", "--no-merges", "--format=%H%x00%aE%x00%aN%x00%at")
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 4 {
+			continue
+		}
+		author := gitGetAuthor(authors, fields[1], fields[2])
+		author.articles = append(author.articles, &gitArticle{
+			repo:      repo,
+			author:    author.name,
+			hash:      fields[0],
+			date:      gitParseTime(fields[3]),
+			minLength: minLength,
+		})
+	}
+	return nil
+}
+
+// gitIndexDocs records every Markdown file in HEAD whose
+// history contains only one author.
+func gitIndexDocs(repo string, authors map[string]*gitAuthor) error {
+	out, err := gitCommand(repo, "ls-files", "-z", "--", "*.md", "*.markdown")
+	if err != nil {
+		return err
+	}
+	for _, path := range strings.Split(string(out), "\x00") {
+		if path == "" {
+			continue
+		}
+		log, err := gitCommand(repo, "log", "--format=%aE%x00%aN%x00%at", "--", path)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(strings.TrimSpace(string(log)), "\n")
+		var email string
+		sole := true
+		for _, line := range lines {
+			fields := strings.Split(line, "\x00")
+			if email != "" && strings.ToLower(fields[0]) != email {
+				sole = false
+				break
+			}
+			email = strings.ToLower(fields[0])
+		}
+		if !sole || email == "" {
+			continue
+		}
+		latest := strings.Split(lines[0], "\x00")
+		author := gitGetAuthor(authors, latest[0], latest[1])
+		author.articles = append(author.articles, &gitArticle{
+			repo:   repo,
+			author: author.name,
+			path:   path,
+			date:   gitParseTime(latest[2]),
+		})
+	}
+	return nil
+}
+
+func gitGetAuthor(authors map[string]*gitAuthor, email, name string) *gitAuthor {
+	key := strings.ToLower(email)
+	if a, ok := authors[key]; ok {
+		return a
+	}
+	a := &gitAuthor{name: name}
+	authors[key] = a
+	return a
+}
+
+func gitParseTime(unix string) time.Time {
+	t, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(t, 0)
+}
+
+func gitCommand(repo string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, errors.New("git " + args[0] + " in " + repo + ": " + msg)
+	}
+	return out, nil
+}
+
+type gitAuthor struct {
+	name     string
+	articles []*gitArticle
+}
+
+func (g *gitAuthor) Name() string {
+	return g.name
+}
+
+func (g *gitAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan error) {
+	artChan := make(chan Article, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(artChan)
+		defer close(errChan)
+		for _, a := range g.articles {
+			select {
+			case <-stop:
+				return
+			case artChan <- a:
+			}
+		}
+	}()
+
+	return artChan, errChan
+}
+
+// A gitArticle is either a commit message (if hash is
+// set) or a Markdown document (if path is set).
+type gitArticle struct {
+	repo      string
+	author    string
+	hash      string
+	path      string
+	date      time.Time
+	minLength int
+}
+
+func (g *gitArticle) ID() string {
+	if g.hash != "" {
+		return hashID("git:" + g.hash)
+	}
+	abs, _ := filepath.Abs(g.repo)
+	return hashID("git:" + abs + ":" + g.path)
+}
+
+func (g *gitArticle) Body() (string, error) {
+	if g.path != "" {
+		data, err := gitCommand(g.repo, "show", "HEAD:"+g.path)
+		if err != nil {
+			return "", err
+		}
+		return markdownText(string(data)), nil
+	}
+
+	data, err := gitCommand(g.repo, "show", "-s", "--format=%B", g.hash)
+	if err != nil {
+		return "", err
+	}
+	msg := gitStripTrailers(string(data))
+	if strings.HasPrefix(msg, `Revert "`) {
+		return "", errors.New("generated revert message")
+	} else if len(msg) < g.minLength {
+		return "", errors.New("commit message too short")
+	}
+	return msg, nil
+}
+
+// Document parses documentation files as Markdown, while
+// commit messages are treated as plain text.
+func (g *gitArticle) Document() (*Document, error) {
+	if g.path != "" {
+		data, err := gitCommand(g.repo, "show", "HEAD:"+g.path)
+		if err != nil {
+			return nil, err
+		}
+		return markdownDocument(string(data)), nil
+	}
+	return bodyDocument(g)
+}
+
+func (g *gitArticle) Date() (time.Time, error) {
+	return g.date, nil
+}
+
+var gitCoAuthor = regexp.MustCompile(`(?im)^\s*co-authored-by:\s*([^<\n]*)`)
+
+// Authors includes anyone credited with a Co-authored-by
+// trailer in a commit message.
+func (g *gitArticle) Authors() ([]string, error) {
+	res := []string{g.author}
+	if g.hash == "" {
+		return res, nil
+	}
+	data, err := gitCommand(g.repo, "show", "-s", "--format=%B", g.hash)
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range gitCoAuthor.FindAllStringSubmatch(string(data), -1) {
+		if name := strings.TrimSpace(match[1]); name != "" {
+			res = append(res, name)
+		}
+	}
+	return uniqueStrings(res), nil
+}
+
+var gitTrailer = regexp.MustCompile(`(?i)^(signed-off-by|co-authored-by|reviewed-by|acked-by|` +
+	`tested-by|reported-by|suggested-by|cc|change-id|git-svn-id):`)
+
+// gitStripTrailers removes sign-offs and other metadata
+// lines from a commit message.
+func gitStripTrailers(msg string) string {
+	var lines []string
+	for _, line := range strings.Split(msg, "\n") {
+		if !gitTrailer.MatchString(strings.TrimSpace(line)) {
+			lines = append(lines, strings.TrimRight(line, " \t"))
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
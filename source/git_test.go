@@ -0,0 +1,90 @@
+package source
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo, err := ioutil.TempDir("", "git_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repo)
+
+	run := func(name, email string, args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME="+name, "GIT_AUTHOR_EMAIL="+email,
+			"GIT_COMMITTER_NAME="+name, "GIT_COMMITTER_EMAIL="+email)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+	write := func(name, contents string) {
+		if err := ioutil.WriteFile(filepath.Join(repo, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("", "", "init", "-q")
+	write(".mailmap", "Jane Doe <jane@example.com> <jdoe@old.example.com>\n")
+	write("GUIDE.md", "# Guide\n\nRead this first.\n\n```\nmake\n```\n\nThen relax.\n")
+	run("Jane Doe", "jane@example.com", "add", ".")
+	run("Jane Doe", "jane@example.com", "commit", "-q", "-m",
+		"Add a guide\n\nExplain how to build.\n\nSigned-off-by: Jane Doe <jane@example.com>")
+
+	write("README.md", "# Project\n")
+	run("J. Doe", "jdoe@old.example.com", "add", ".")
+	run("J. Doe", "jdoe@old.example.com", "commit", "-q", "-m", "Add a readme")
+
+	write("README.md", "# Project\n\nNow with more words.\n")
+	run("John Roe", "john@example.com", "commit", "-q", "-a", "-m",
+		"Expand the readme\n\nCo-authored-by: Jane Doe <jane@example.com>")
+
+	setEnv(t, map[string]string{"GIT_REPOS": repo, "GIT_DOCS": "1"})
+
+	authors := collectAuthors(t, Git{})
+	if len(authors) != 2 || authors[0].Name() != "Jane Doe" ||
+		authors[1].Name() != "John Roe" {
+		t.Fatal("unexpected authors:", authors)
+	}
+
+	arts := collectArticles(t, authors[0])
+	var bodies []string
+	for _, a := range arts {
+		body, err := a.Body()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies = append(bodies, body)
+	}
+	expected := []string{
+		"Add a readme",
+		"Add a guide\n\nExplain how to build.",
+		"Guide\n\nRead this first.\n\nThen relax.",
+	}
+	if len(bodies) != len(expected) {
+		t.Fatalf("expected %d articles but got %q", len(expected), bodies)
+	}
+	for i, x := range expected {
+		if bodies[i] != x {
+			t.Errorf("article %d: expected %q but got %q", i, x, bodies[i])
+		}
+	}
+
+	// README.md has two authors, so it is not included.
+	if arts := collectArticles(t, authors[1]); len(arts) != 1 {
+		t.Errorf("expected 1 article for John but got %d", len(arts))
+	} else if names, err := arts[0].Authors(); err != nil || len(names) != 2 ||
+		names[0] != "John Roe" || names[1] != "Jane Doe" {
+		t.Errorf("unexpected co-authors: %v (%v)", names, err)
+	}
+}
@@ -0,0 +1,381 @@
+package source
+
+import (
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Gutenberg is a Source that reads books from a local
+// Project Gutenberg mirror.
+//
+// Author and language metadata come from the RDF catalog.
+// Works with several authors or a translator are skipped,
+// and each book is split into chapter-sized articles.
+type Gutenberg struct{}
+
+// Help returns the usage information for this Source.
+func (_ Gutenberg) Help() string {
+	return "Read books from a local Project Gutenberg mirror.\n" +
+		"Environment variables:\n" +
+		" GUTENBERG_DIR        root of the mirror (required)\n" +
+		" GUTENBERG_CATALOG    directory of RDF files (default\n" +
+		"                      $GUTENBERG_DIR/cache/epub)\n" +
+		" GUTENBERG_LANGUAGE   language code to keep (default en)\n" +
+		" GUTENBERG_CHUNK      article size when a book has no\n" +
+		"                      chapter headings (default 20000)"
+}
+
+// Authors reads the catalog and produces every author of
+// at least one eligible book with a text file.
+func (_ Gutenberg) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+	authChan := make(chan Author, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(authChan)
+		defer close(errChan)
+
+		dir := os.Getenv("GUTENBERG_DIR")
+		if dir == "" {
+			errChan <- errors.New("missing GUTENBERG_DIR")
+			return
+		}
+		catalog := os.Getenv("GUTENBERG_CATALOG")
+		if catalog == "" {
+			catalog = filepath.Join(dir, "cache", "epub")
+		}
+		language := os.Getenv("GUTENBERG_LANGUAGE")
+		if language == "" {
+			language = "en"
+		}
+		chunkSize, err := envInt("GUTENBERG_CHUNK", 20000)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		authors := map[string]*gutenbergAuthor{}
+		err = filepath.Walk(catalog, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || filepath.Ext(path) != ".rdf" {
+				return err
+			}
+			book, err := gutenbergReadRDF(path)
+			if err != nil {
+				return errors.New(path + ": " + err.Error())
+			}
+			if book == nil || book.language != language {
+				return nil
+			}
+			book.textPath = gutenbergTextPath(dir, book.id)
+			if book.textPath == "" {
+				return nil
+			}
+			book.chunkSize = chunkSize
+			author, ok := authors[book.agentID]
+			if !ok {
+				author = &gutenbergAuthor{name: book.authorName}
+				authors[book.agentID] = author
+			}
+			author.books = append(author.books, book)
+			return nil
+		})
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		var ids []string
+		for id := range authors {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			select {
+			case <-stop:
+				return
+			case authChan <- authors[id]:
+			}
+		}
+	}()
+
+	return authChan, errChan
+}
+
+// gutenbergRDF is the part of a catalog entry that we
+// use. Namespaces are ignored, since the local names are
+// unambiguous.
+type gutenbergRDF struct {
+	Ebook struct {
+		About    string `xml:"about,attr"`
+		Creators []struct {
+			Agent gutenbergAgent `xml:"agent"`
+		} `xml:"creator"`
+		Translators []struct {
+			Agent gutenbergAgent `xml:"agent"`
+		} `xml:"trl"`
+		Languages []string `xml:"language>Description>value"`
+		Issued    string   `xml:"issued"`
+	} `xml:"ebook"`
+}
+
+type gutenbergAgent struct {
+	About string `xml:"about,attr"`
+	Name  string `xml:"name"`
+}
+
+// gutenbergReadRDF reads a catalog entry, returning nil if
+// the book is not the work of a single, known author in
+// its original language.
+func gutenbergReadRDF(path string) (*gutenbergBook, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rdf gutenbergRDF
+	if err := xml.Unmarshal(data, &rdf); err != nil {
+		return nil, err
+	}
+	ebook := rdf.Ebook
+	if len(ebook.Creators) != 1 || len(ebook.Translators) != 0 ||
+		len(ebook.Languages) != 1 {
+		return nil, nil
+	}
+	agent := ebook.Creators[0].Agent
+	switch agent.Name {
+	case "", "Anonymous", "Various", "Unknown":
+		return nil, nil
+	}
+	id := strings.TrimPrefix(ebook.About, "ebooks/")
+	if _, err := strconv.Atoi(id); err != nil {
+		return nil, errors.New("unexpected ebook ID: " + ebook.About)
+	}
+	issued, _ := time.Parse("2006-01-02", ebook.Issued)
+	return &gutenbergBook{
+		id:         id,
+		agentID:    agent.About,
+		authorName: gutenbergFormatName(agent.Name),
+		language:   ebook.Languages[0],
+		issued:     issued,
+	}, nil
+}
+
+// gutenbergFormatName turns a catalog name like
+// "Austen, Jane" into "Jane Austen".
+func gutenbergFormatName(name string) string {
+	parts := strings.SplitN(name, ", ", 2)
+	if len(parts) != 2 {
+		return name
+	}
+	return parts[1] + " " + parts[0]
+}
+
+// gutenbergTextPath finds the plain text file for a book,
+// returning "" if there is none.
+func gutenbergTextPath(dir, id string) string {
+	var nested []string
+	for _, c := range id[:len(id)-1] {
+		nested = append(nested, string(c))
+	}
+	if len(nested) == 0 {
+		nested = []string{"0"}
+	}
+	nestedDir := filepath.Join(dir, filepath.Join(nested...), id)
+	cacheDir := filepath.Join(dir, "cache", "epub", id)
+	candidates := []string{
+		filepath.Join(nestedDir, id+"-0.txt"),
+		filepath.Join(nestedDir, id+".txt"),
+		filepath.Join(cacheDir, "pg"+id+".txt"),
+	}
+	for _, c := range candidates {
+		if _, err := os.Stat(c); err == nil {
+			return c
+		}
+	}
+	return ""
+}
+
+type gutenbergBook struct {
+	id         string
+	agentID    string
+	authorName string
+	language   string
+	issued     time.Time
+	textPath   string
+	chunkSize  int
+}
+
+// gutenbergSection is a piece of a book's text, along with
+// its byte offset in the text once the boilerplate has
+// been stripped.
+type gutenbergSection struct {
+	offset int
+	text   string
+}
+
+// chapters reads the book, strips the Gutenberg header
+// and license, and splits it into articles.
+func (g *gutenbergBook) chapters() ([]gutenbergSection, error) {
+	data, err := ioutil.ReadFile(g.textPath)
+	if err != nil {
+		return nil, err
+	}
+	text := gutenbergStripBoilerplate(string(data))
+	if text == "" {
+		return nil, errors.New("no text in " + g.textPath)
+	}
+	if chapters := gutenbergSplitChapters(text, g.chunkSize); len(chapters) > 1 {
+		return chapters, nil
+	}
+	return gutenbergSplitSize(text, g.chunkSize), nil
+}
+
+var (
+	gutenbergStart = regexp.MustCompile(`(?m)^\*\*\* ?START OF (THE|THIS) PROJECT GUTENBERG.*$`)
+	gutenbergEnd   = regexp.MustCompile(`(?m)^(\*\*\* ?END OF (THE|THIS) PROJECT GUTENBERG|` +
+		`End of (the )?Project Gutenberg)`)
+	gutenbergCredit = regexp.MustCompile(`^(Produced by|Transcribed by|E-text prepared by)`)
+	gutenbergHeader = regexp.MustCompile(`(?m)^(CHAPTER|Chapter|BOOK|Book|PART|Part)[ \t]+` +
+		`([IVXLCDM]+|[0-9]+|[A-Z][a-z]+|[A-Z]+)\b.*$`)
+)
+
+func gutenbergStripBoilerplate(text string) string {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	if loc := gutenbergStart.FindStringIndex(text); loc != nil {
+		text = text[loc[1]:]
+	}
+	if loc := gutenbergEnd.FindStringIndex(text); loc != nil {
+		text = text[:loc[0]]
+	}
+	paragraphs := strings.Split(strings.TrimSpace(text), "\n\n")
+	for len(paragraphs) > 0 && gutenbergCredit.MatchString(paragraphs[0]) {
+		paragraphs = paragraphs[1:]
+	}
+	return strings.TrimSpace(strings.Join(paragraphs, "\n\n"))
+}
+
+// gutenbergSplitChapters splits a book at chapter headings.
+// Sections shorter than a tenth of the chunk size (such as
+// table of contents entries) are discarded.
+func gutenbergSplitChapters(text string, chunkSize int) []gutenbergSection {
+	locs := gutenbergHeader.FindAllStringIndex(text, -1)
+	var res []gutenbergSection
+	for i, loc := range locs {
+		end := len(text)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		chapter := strings.TrimSpace(text[loc[1]:end])
+		if len(chapter) >= chunkSize/10 {
+			res = append(res, gutenbergSection{offset: loc[0], text: chapter})
+		}
+	}
+	return res
+}
+
+// gutenbergSplitSize splits text into chunks of roughly
+// the given size at paragraph boundaries.
+func gutenbergSplitSize(text string, chunkSize int) []gutenbergSection {
+	var res []gutenbergSection
+	var current []string
+	var start, offset, size int
+	for _, p := range strings.Split(text, "\n\n") {
+		if len(current) == 0 {
+			start = offset
+		}
+		current = append(current, p)
+		offset += len(p) + 2
+		size += len(p)
+		if size >= chunkSize {
+			res = append(res, gutenbergSection{
+				offset: start,
+				text:   strings.TrimSpace(strings.Join(current, "\n\n")),
+			})
+			current = nil
+			size = 0
+		}
+	}
+	if len(current) > 0 {
+		res = append(res, gutenbergSection{
+			offset: start,
+			text:   strings.TrimSpace(strings.Join(current, "\n\n")),
+		})
+	}
+	return res
+}
+
+type gutenbergAuthor struct {
+	name  string
+	books []*gutenbergBook
+}
+
+func (g *gutenbergAuthor) Name() string {
+	return g.name
+}
+
+func (g *gutenbergAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan error) {
+	artChan := make(chan Article, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(artChan)
+		defer close(errChan)
+		for _, book := range g.books {
+			chapters, err := book.chapters()
+			if err != nil {
+				log.Println("skipping Gutenberg book:", err)
+				continue
+			}
+			for _, section := range chapters {
+				art := &gutenbergChapter{book: book, offset: section.offset, text: section.text}
+				select {
+				case <-stop:
+					return
+				case artChan <- art:
+				}
+			}
+		}
+	}()
+
+	return artChan, errChan
+}
+
+// gutenbergChapter is one section of a book. Its ID is
+// based on the section's position in the text, so that it
+// does not depend on which other sections were kept.
+type gutenbergChapter struct {
+	book   *gutenbergBook
+	offset int
+	text   string
+}
+
+func (g *gutenbergChapter) ID() string {
+	return hashID("gutenberg:" + g.book.id + ":" + strconv.Itoa(g.offset))
+}
+
+func (g *gutenbergChapter) Body() (string, error) {
+	return g.text, nil
+}
+
+func (g *gutenbergChapter) Document() (*Document, error) {
+	return bodyDocument(g)
+}
+
+// Date returns the date the book was released on Project
+// Gutenberg, since original publication dates are not in
+// the catalog.
+func (g *gutenbergChapter) Date() (time.Time, error) {
+	return g.book.issued, nil
+}
+
+func (g *gutenbergChapter) Authors() ([]string, error) {
+	return []string{g.book.authorName}, nil
+}
@@ -0,0 +1,87 @@
+package source
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGutenberg(t *testing.T) {
+	setEnv(t, map[string]string{
+		"GUTENBERG_DIR":   filepath.Join("testdata", "gutenberg"),
+		"GUTENBERG_CHUNK": "100",
+	})
+
+	authors := collectAuthors(t, Gutenberg{})
+	if len(authors) != 1 || authors[0].Name() != "Jane Austen" {
+		t.Fatal("unexpected authors:", authors)
+	}
+
+	// Book 1000 has no text, and must be skipped without
+	// ending the listing.
+	arts := collectArticles(t, authors[0])
+	var bodies []string
+	ids := map[string]bool{}
+	for _, a := range arts {
+		body, err := a.Body()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies = append(bodies, body)
+		ids[a.ID()] = true
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("expected 3 articles but got %q", bodies)
+	}
+	if !strings.HasPrefix(bodies[0], "It is a truth") ||
+		!strings.HasSuffix(bodies[0], "such a man may be.") {
+		t.Errorf("unexpected first chapter: %q", bodies[0])
+	}
+	if !strings.HasPrefix(bodies[1], "Mr. Bennet") ||
+		!strings.HasSuffix(bodies[1], "to visit him.") {
+		t.Errorf("unexpected second chapter: %q", bodies[1])
+	}
+	if !strings.HasPrefix(bodies[2], "The first essay") ||
+		!strings.HasSuffix(bodies[2], "third essay paragraph.") {
+		t.Errorf("unexpected essay chunk: %q", bodies[2])
+	}
+	if len(ids) != 3 {
+		t.Error("article IDs are not unique")
+	}
+	if date, _ := arts[2].Date(); date.Year() != 2001 {
+		t.Errorf("unexpected date: %v", date)
+	}
+}
+
+func TestGutenbergChapterIDs(t *testing.T) {
+	// The contents entry for the first chapter is only kept
+	// with the smaller chunk size, which must not renumber
+	// the chapters after it.
+	ids := map[string]string{}
+	for _, chunk := range []string{"100", "50"} {
+		setEnv(t, map[string]string{
+			"GUTENBERG_DIR":   filepath.Join("testdata", "gutenberg"),
+			"GUTENBERG_CHUNK": chunk,
+		})
+		authors := collectAuthors(t, Gutenberg{})
+		if len(authors) != 1 {
+			t.Fatal("unexpected authors:", authors)
+		}
+		for _, a := range collectArticles(t, authors[0]) {
+			body, err := a.Body()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(body, "It is a truth") && !strings.HasPrefix(body, "Mr. Bennet") {
+				continue
+			}
+			if id, ok := ids[body]; ok && id != a.ID() {
+				t.Errorf("chunk size %s: chapter ID changed from %s to %s", chunk, id, a.ID())
+			}
+			ids[body] = a.ID()
+		}
+	}
+	if len(ids) != 2 {
+		t.Errorf("expected 2 chapters but got %d", len(ids))
+	}
+}
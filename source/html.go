@@ -0,0 +1,99 @@
+package source
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"net/url"
+	"strings"
+
+	"github.com/yhat/scrape"
+
+	"golang.org/x/net/html"
+)
+
+// removeNodes detaches every node under root which
+// matches m.
+func removeNodes(root *html.Node, m scrape.Matcher) {
+	for _, n := range scrape.FindAll(root, m) {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+// cloneNode makes a deep copy of a node and its children,
+// leaving out its parent and siblings.
+func cloneNode(n *html.Node) *html.Node {
+	res := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute(nil), n.Attr...),
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		res.AppendChild(cloneNode(c))
+	}
+	return res
+}
+
+// joinParagraphs extracts the trimmed text of each node,
+// joining non-empty results with blank lines.
+func joinParagraphs(nodes []*html.Node) string {
+	var paraText []string
+	for _, p := range nodes {
+		if text := nodeText(p); text != "" {
+			paraText = append(paraText, text)
+		}
+	}
+	return strings.Join(paraText, "\n\n")
+}
+
+// htmlText converts an HTML fragment to plain text,
+// dropping code blocks and separating paragraphs, list
+// items and headings with blank lines.
+func htmlText(fragment string) string {
+	return htmlFragmentDocument(fragment).Text()
+}
+
+// htmlFragmentDocument parses an HTML fragment and
+// converts it to a Document.
+func htmlFragmentDocument(fragment string) *Document {
+	parsed, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		return &Document{}
+	}
+	return htmlDocument(parsed)
+}
+
+// nodeText returns the text inside a node with runs of
+// whitespace collapsed.
+// Unlike scrape.Text, it does not insert spaces between
+// adjacent text nodes, so "<b>bold</b>." stays intact.
+func nodeText(n *html.Node) string {
+	raw := scrape.TextJoin(n, func(s []string) string {
+		return strings.Join(s, "")
+	})
+	return strings.Join(strings.Fields(raw), " ")
+}
+
+// resolveURL resolves a possibly relative link against a
+// base URL.
+func resolveURL(base, link string) string {
+	b, err := url.Parse(base)
+	if err != nil {
+		return link
+	}
+	l, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return b.ResolveReference(l).String()
+}
+
+// hashID produces a uniform-length article ID from an
+// identifying string such as a URL.
+func hashID(s string) string {
+	hash := md5.Sum([]byte(s))
+	return strings.ToLower(hex.EncodeToString(hash[:]))
+}
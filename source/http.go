@@ -0,0 +1,71 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/net/html"
+)
+
+// HTTPClient is the client used by every Source that
+// fetches data over HTTP.
+// It may be replaced, e.g. to send cookies.
+var HTTPClient = http.DefaultClient
+
+// An HTTPError is returned when a server responds with a
+// non-2xx status code.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+}
+
+func (h *HTTPError) Error() string {
+	return fmt.Sprintf("GET %s: status %d", h.URL, h.StatusCode)
+}
+
+// isNotFound checks if err is an *HTTPError for a 404
+// response.
+func isNotFound(err error) bool {
+	httpErr, ok := err.(*HTTPError)
+	return ok && httpErr.StatusCode == http.StatusNotFound
+}
+
+// httpGet is like client.Get, but it returns an
+// *HTTPError for unsuccessful status codes.
+// A nil client means HTTPClient.
+func httpGet(client *http.Client, u string) (*http.Response, error) {
+	if client == nil {
+		client = HTTPClient
+	}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, &HTTPError{URL: u, StatusCode: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// getHTML fetches and parses an HTML page.
+func getHTML(client *http.Client, u string) (*html.Node, error) {
+	resp, err := httpGet(client, u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return html.Parse(resp.Body)
+}
+
+// getJSON fetches a URL and decodes the JSON response
+// into v.
+func getJSON(client *http.Client, u string, v interface{}) error {
+	resp, err := httpGet(client, u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,295 @@
+package source
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Manifest is a Source that reads a CSV or TSV file of
+// (author, location) rows, where each location is either
+// a URL or a local file.
+//
+// Remote pages are scraped with the same extractor as the
+// News source.
+// Local files may be plain text, HTML or Markdown.
+type Manifest struct {
+	// client makes the requests for the source, or is
+	// nil to use HTTPClient.
+	client *http.Client
+}
+
+// Help returns the usage information for this Source.
+func (_ Manifest) Help() string {
+	return "Read a manifest of (author, url or path) rows.\n" +
+		"Environment variables:\n" +
+		" MANIFEST_FILE   path to a .csv or .tsv manifest (required)\n" +
+		"Each row is author,location[,date] where the optional date\n" +
+		"is in YYYY-MM-DD or RFC 3339 format. A first row starting\n" +
+		"with \"author\" is treated as a header. Relative paths are\n" +
+		"resolved against the manifest's directory. Local files may\n" +
+		"be .txt, .html or .md. A location listed under several authors\n" +
+		"is treated as a co-authored piece."
+}
+
+// withClient returns a copy of the source which makes its
+// requests with client.
+func (m Manifest) withClient(client *http.Client) Source {
+	m.client = client
+	return m
+}
+
+// Authors produces the authors in the order in which they
+// first appear in the manifest.
+func (m Manifest) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+	authChan := make(chan Author, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(authChan)
+		defer close(errChan)
+
+		path := os.Getenv("MANIFEST_FILE")
+		if path == "" {
+			errChan <- errors.New("missing MANIFEST_FILE")
+			return
+		}
+		authors, err := manifestRead(m.client, path)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		for _, a := range authors {
+			select {
+			case <-stop:
+				return
+			case authChan <- a:
+			}
+		}
+	}()
+
+	return authChan, errChan
+}
+
+func manifestRead(client *http.Client, path string) ([]*manifestAuthor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	if strings.ToLower(filepath.Ext(path)) == ".tsv" {
+		r.Comma = '\t'
+		r.LazyQuotes = true
+	}
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var res []*manifestAuthor
+	byName := map[string]*manifestAuthor{}
+	for line := 1; true; line++ {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(row[0]), "author") {
+			continue
+		}
+		if len(row) < 2 || len(row) > 3 {
+			return nil, errors.New(path + ": expected 2 or 3 columns in row: " +
+				strings.Join(row, ","))
+		}
+		name, location := strings.TrimSpace(row[0]), strings.TrimSpace(row[1])
+		if name == "" || location == "" {
+			continue
+		}
+		entry := &manifestArticle{location: location, client: client}
+		if !manifestIsURL(location) && !filepath.IsAbs(location) {
+			entry.location = filepath.Join(filepath.Dir(path), location)
+		}
+		if len(row) == 3 && strings.TrimSpace(row[2]) != "" {
+			entry.date = parseDate(row[2])
+			if entry.date.IsZero() {
+				return nil, errors.New(path + ": invalid date: " + row[2])
+			}
+		}
+
+		author, ok := byName[name]
+		if !ok {
+			author = &manifestAuthor{name: name}
+			byName[name] = author
+			res = append(res, author)
+		}
+		author.articles = append(author.articles, entry)
+	}
+
+	coauthors := map[string][]string{}
+	for _, author := range res {
+		for _, entry := range author.articles {
+			coauthors[entry.location] = append(coauthors[entry.location], author.name)
+		}
+	}
+	for _, author := range res {
+		for _, entry := range author.articles {
+			entry.authors = uniqueStrings(coauthors[entry.location])
+		}
+	}
+	return res, nil
+}
+
+func manifestIsURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+type manifestAuthor struct {
+	name     string
+	articles []*manifestArticle
+}
+
+func (m *manifestAuthor) Name() string {
+	return m.name
+}
+
+func (m *manifestAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan error) {
+	artChan := make(chan Article, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(artChan)
+		defer close(errChan)
+		for _, a := range m.articles {
+			select {
+			case <-stop:
+				return
+			case artChan <- a:
+			}
+		}
+	}()
+
+	return artChan, errChan
+}
+
+type manifestArticle struct {
+	location string
+	date     time.Time
+	authors  []string
+	client   *http.Client
+
+	extracted *extractedPage
+}
+
+func (m *manifestArticle) ID() string {
+	if manifestIsURL(m.location) {
+		return hashID(m.location)
+	}
+	abs, _ := filepath.Abs(m.location)
+	return hashID(abs)
+}
+
+func (m *manifestArticle) URL() string {
+	return m.location
+}
+
+func (m *manifestArticle) Body() (string, error) {
+	if manifestIsURL(m.location) {
+		extracted, err := m.extract()
+		if err != nil {
+			return "", err
+		}
+		return manifestBody(extracted.Body)
+	}
+
+	data, err := ioutil.ReadFile(m.location)
+	if err != nil {
+		return "", err
+	}
+	switch strings.ToLower(filepath.Ext(m.location)) {
+	case ".txt", ".text":
+		return manifestBody(strings.TrimSpace(string(data)))
+	case ".md", ".markdown":
+		return manifestBody(markdownText(string(data)))
+	case ".html", ".htm", ".xhtml":
+		page, err := html.Parse(strings.NewReader(string(data)))
+		if err != nil {
+			return "", err
+		}
+		return manifestBody(extractPage(page).Body)
+	}
+	return "", errors.New("unsupported file type: " + m.location)
+}
+
+// Document keeps the structure of local Markdown files.
+// Other articles are split into paragraphs of prose.
+func (m *manifestArticle) Document() (*Document, error) {
+	switch strings.ToLower(filepath.Ext(m.location)) {
+	case ".md", ".markdown":
+		if !manifestIsURL(m.location) {
+			data, err := ioutil.ReadFile(m.location)
+			if err != nil {
+				return nil, err
+			}
+			doc := markdownDocument(string(data))
+			if len(doc.Blocks) == 0 {
+				return nil, errors.New("no body text found")
+			}
+			return doc, nil
+		}
+	}
+	return bodyDocument(m)
+}
+
+func manifestBody(body string) (string, error) {
+	if body == "" {
+		return "", errors.New("no body text found")
+	}
+	return body, nil
+}
+
+// Date returns the date from the manifest if there is one.
+// Otherwise, it uses the modification time for local files
+// and the page metadata for URLs.
+func (m *manifestArticle) Date() (time.Time, error) {
+	if !m.date.IsZero() {
+		return m.date, nil
+	}
+	if manifestIsURL(m.location) {
+		extracted, err := m.extract()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return extracted.Date, nil
+	}
+	info, err := os.Stat(m.location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+func (m *manifestArticle) Authors() ([]string, error) {
+	return m.authors, nil
+}
+
+// extract fetches and extracts a remote page, caching the
+// result so that Body and Date share one request.
+func (m *manifestArticle) extract() (*extractedPage, error) {
+	if m.extracted == nil {
+		page, err := getHTML(m.client, m.location)
+		if err != nil {
+			return nil, err
+		}
+		m.extracted = extractPage(page)
+	}
+	return m.extracted, nil
+}
@@ -0,0 +1,90 @@
+package source
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	server := httptest.NewServer(nil)
+	defer server.Close()
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveFixture(w, "news"+r.URL.Path, server.URL)
+	})
+
+	// Copy the manifest to a temporary directory, filling
+	// in the server URL and making the paths of its files
+	// absolute. The TSV manifest covers relative paths.
+	dir := filepath.Join("testdata", "manifest")
+	data, err := ioutil.ReadFile(filepath.Join(dir, "manifest.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := filepath.Join(t.TempDir(), "manifest.csv")
+	data = []byte(strings.NewReplacer(
+		"{{server}}", server.URL,
+		",files/", ","+filepath.Join(absDir, "files")+string(filepath.Separator),
+	).Replace(string(data)))
+	if err := ioutil.WriteFile(manifest, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	setEnv(t, map[string]string{"MANIFEST_FILE": manifest})
+
+	authors := collectAuthors(t, Manifest{})
+	var names []string
+	for _, a := range authors {
+		names = append(names, a.Name())
+	}
+	if strings.Join(names, "|") != "Jane Doe|John Roe|Roe, John" {
+		t.Fatal("unexpected authors:", names)
+	}
+
+	expected := [][]string{
+		{
+			"An essay in plain text.\n\nSecond paragraph.",
+			"The opening paragraph, which has plenty of words, commas, and clauses.\n\n" +
+				"A second paragraph, also fairly long, to anchor the body here.\n\nShort.",
+		},
+		{"A post\n\nSome prose.\n\nMore prose."},
+		{"A local HTML page with a reasonably long paragraph."},
+	}
+	expectedDays := [][]int{{20, 20}, {-1}, {18}}
+	for i, author := range authors {
+		arts := collectArticles(t, author)
+		if len(arts) != len(expected[i]) {
+			t.Fatalf("%s: expected %d articles but got %d", names[i], len(expected[i]),
+				len(arts))
+		}
+		for j, art := range arts {
+			body, err := art.Body()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body != expected[i][j] {
+				t.Errorf("%s article %d: unexpected body %q", names[i], j, body)
+			}
+			date, err := art.Date()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if day := expectedDays[i][j]; day > 0 && date.Day() != day {
+				t.Errorf("%s article %d: unexpected date %v", names[i], j, date)
+			}
+		}
+	}
+
+	setEnv(t, map[string]string{"MANIFEST_FILE": filepath.Join(dir, "manifest.tsv")})
+	authors = collectAuthors(t, Manifest{})
+	if len(authors) != 1 || len(collectArticles(t, authors[0])) != 1 {
+		t.Error("failed to read TSV manifest")
+	}
+}
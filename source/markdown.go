@@ -0,0 +1,122 @@
+package source
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	markdownHeading   = regexp.MustCompile(`^#{1,6}\s+`)
+	markdownSetext    = regexp.MustCompile(`^(=+|-+)$`)
+	markdownRule      = regexp.MustCompile(`^([-*_]\s*){3,}$`)
+	markdownListItem  = regexp.MustCompile(`^([-*+]|\d+[.)])\s+`)
+	markdownRefDef    = regexp.MustCompile(`^\[[^\]]+\]:\s*\S+`)
+	markdownQuote     = regexp.MustCompile(`^(>\s?)+`)
+	markdownImage     = regexp.MustCompile(`!\[[^\]]*\](\([^)]*\)|\[[^\]]*\])`)
+	markdownLink      = regexp.MustCompile(`\[([^\]]*)\](\([^)]*\)|\[[^\]]*\])`)
+	markdownAutolink  = regexp.MustCompile(`<[a-z]+:[^>\s]*>`)
+	markdownTag       = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	markdownCode      = regexp.MustCompile("`[^`]*`")
+	markdownTemplate  = regexp.MustCompile(`\{\{.*?\}\}|\{%.*?%\}`)
+	markdownStrong    = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	markdownEmphasis  = regexp.MustCompile(`\*(\S(?:[^*]*\S)?)\*`)
+	markdownUnderline = regexp.MustCompile(`(^|\W)_(\S(?:[^_]*\S)?)_(\W|$)`)
+)
+
+// markdownText converts a Markdown document to prose.
+func markdownText(doc string) string {
+	return markdownDocument(doc).Text()
+}
+
+// markdownDocument converts a Markdown document to a
+// Document.
+//
+// Code blocks, images, HTML tags and site-generator
+// template tags are dropped, while headings, block quotes,
+// list items and paragraphs become separate blocks.
+// Hard-wrapped paragraphs are joined into single lines.
+func markdownDocument(doc string) *Document {
+	res := &Document{}
+	var cur []string
+	curType := Prose
+	flush := func() {
+		text := strings.Join(strings.Fields(strings.Join(cur, " ")), " ")
+		res.addBlock(Block{Type: curType, Text: text})
+		cur = nil
+		curType = Prose
+	}
+
+	var fence string
+	for _, line := range strings.Split(doc, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			flush()
+			fence = trimmed[:3]
+			continue
+		}
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		if len(cur) == 0 && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")) {
+			// Indented code block.
+			continue
+		}
+
+		if markdownQuote.MatchString(trimmed) {
+			if curType != Quote {
+				flush()
+				curType = Quote
+			}
+			trimmed = markdownQuote.ReplaceAllString(trimmed, "")
+			if trimmed == "" {
+				flush()
+				continue
+			}
+		}
+		switch {
+		case len(cur) > 0 && markdownSetext.MatchString(trimmed):
+			curType = Heading
+			flush()
+		case markdownRule.MatchString(trimmed):
+			flush()
+		case markdownRefDef.MatchString(trimmed):
+		case markdownHeading.MatchString(trimmed):
+			flush()
+			heading := markdownHeading.ReplaceAllString(trimmed, "")
+			cur = append(cur, markdownInline(strings.TrimRight(heading, "# ")))
+			curType = Heading
+			flush()
+		case markdownListItem.MatchString(trimmed):
+			if curType != Quote {
+				flush()
+				curType = ListItem
+			}
+			cur = append(cur, markdownInline(markdownListItem.ReplaceAllString(trimmed, "")))
+		default:
+			cur = append(cur, markdownInline(trimmed))
+		}
+	}
+	flush()
+	return res
+}
+
+// markdownInline strips the inline markup from a line.
+func markdownInline(line string) string {
+	line = markdownCode.ReplaceAllString(line, "")
+	line = markdownTemplate.ReplaceAllString(line, "")
+	line = markdownImage.ReplaceAllString(line, "")
+	line = markdownLink.ReplaceAllString(line, "$1")
+	line = markdownAutolink.ReplaceAllString(line, "")
+	line = markdownTag.ReplaceAllString(line, "")
+	line = markdownStrong.ReplaceAllString(line, "$2")
+	line = markdownEmphasis.ReplaceAllString(line, "$1")
+	line = markdownUnderline.ReplaceAllString(line, "$1$2$3")
+	return line
+}
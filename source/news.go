@@ -0,0 +1,262 @@
+package source
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// News is a Source that scrapes arbitrary news sites using
+// the schema.org JSON-LD, OpenGraph and meta tags that
+// most of them embed, falling back on a readability-style
+// heuristic to find the body text.
+//
+// Articles are listed explicitly or through sitemaps, and
+// every page is fetched up front so that articles can be
+// grouped by author.
+// Pages that cannot be fetched or have no author are
+// skipped, and co-authored pages are listed under each of
+// their authors.
+type News struct {
+	// client makes the requests for the source, or is
+	// nil to use HTTPClient.
+	client *http.Client
+}
+
+// Help returns the usage information for this Source.
+func (_ News) Help() string {
+	return "Scrape news articles using JSON-LD and meta tags.\n" +
+		"Environment variables:\n" +
+		" NEWS_URLS       comma-separated article or sitemap URLs\n" +
+		" NEWS_URL_FILE   file with one article or sitemap URL per line\n" +
+		"URLs ending in .xml are treated as sitemaps (or sitemap indexes)."
+}
+
+// withClient returns a copy of the source which makes its
+// requests with client.
+func (n News) withClient(client *http.Client) Source {
+	n.client = client
+	return n
+}
+
+// Authors fetches every listed article and then produces
+// the authors in alphabetical order.
+func (n News) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+	authChan := make(chan Author, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(authChan)
+		defer close(errChan)
+
+		urls, err := newsURLs(n.client)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		authors := map[string]*newsAuthor{}
+		for _, u := range urls {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			page, err := getHTML(n.client, u)
+			if err != nil {
+				log.Println("skipping news page:", u, err)
+				continue
+			}
+			extracted := extractPage(page)
+			if extracted.Body == "" {
+				continue
+			}
+			art := &newsArticle{url: u, page: extracted}
+			for _, name := range extracted.Authors {
+				if authors[name] == nil {
+					authors[name] = &newsAuthor{name: name}
+				}
+				authors[name].articles = append(authors[name].articles, art)
+			}
+		}
+
+		var names []string
+		for name := range authors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			select {
+			case <-stop:
+				return
+			case authChan <- authors[name]:
+			}
+		}
+	}()
+
+	return authChan, errChan
+}
+
+// newsURLs gathers the article URLs from the environment,
+// expanding any sitemaps.
+func newsURLs(client *http.Client) ([]string, error) {
+	var listed []string
+	if env := os.Getenv("NEWS_URLS"); env != "" {
+		listed = strings.Split(env, ",")
+	}
+	if path := os.Getenv("NEWS_URL_FILE"); path != "" {
+		lines, err := readLines(path)
+		if err != nil {
+			return nil, err
+		}
+		listed = append(listed, lines...)
+	}
+	if len(listed) == 0 {
+		return nil, errors.New("missing NEWS_URLS or NEWS_URL_FILE")
+	}
+
+	var res []string
+	for _, u := range listed {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		if !isSitemapURL(u) {
+			res = append(res, u)
+			continue
+		}
+		urls, err := sitemapURLs(client, u)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, urls...)
+	}
+	return uniqueStrings(res), nil
+}
+
+// readLines reads the non-empty lines of a file.
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var res []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			res = append(res, line)
+		}
+	}
+	return res, scanner.Err()
+}
+
+func isSitemapURL(u string) bool {
+	return strings.HasSuffix(strings.SplitN(u, "?", 2)[0], ".xml")
+}
+
+// sitemapURLs lists the pages in a sitemap, recursively
+// following sitemap indexes.
+func sitemapURLs(client *http.Client, u string) ([]string, error) {
+	res, sitemaps, err := readSitemap(client, u)
+	if err != nil {
+		return nil, err
+	}
+	for _, loc := range sitemaps {
+		urls, err := sitemapURLs(client, loc)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, urls...)
+	}
+	return res, nil
+}
+
+// readSitemap reads the page URLs from a sitemap, or the
+// sitemap URLs from a sitemap index.
+func readSitemap(client *http.Client, u string) (urls, sitemaps []string, err error) {
+	resp, err := httpGet(client, u)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	var sitemap struct {
+		URLs     []string `xml:"url>loc"`
+		Sitemaps []string `xml:"sitemap>loc"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&sitemap); err != nil {
+		return nil, nil, errors.New(u + ": " + err.Error())
+	}
+
+	for _, loc := range sitemap.URLs {
+		urls = append(urls, strings.TrimSpace(loc))
+	}
+	for _, loc := range sitemap.Sitemaps {
+		sitemaps = append(sitemaps, strings.TrimSpace(loc))
+	}
+	return urls, sitemaps, nil
+}
+
+type newsAuthor struct {
+	name     string
+	articles []*newsArticle
+}
+
+func (n *newsAuthor) Name() string {
+	return n.name
+}
+
+func (n *newsAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan error) {
+	artChan := make(chan Article, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(artChan)
+		defer close(errChan)
+		for _, a := range n.articles {
+			select {
+			case <-stop:
+				return
+			case artChan <- a:
+			}
+		}
+	}()
+
+	return artChan, errChan
+}
+
+type newsArticle struct {
+	url  string
+	page *extractedPage
+}
+
+func (n *newsArticle) ID() string {
+	return hashID(n.url)
+}
+
+func (n *newsArticle) URL() string {
+	return n.url
+}
+
+func (n *newsArticle) Body() (string, error) {
+	return n.page.Body, nil
+}
+
+func (n *newsArticle) Document() (*Document, error) {
+	return bodyDocument(n)
+}
+
+func (n *newsArticle) Date() (time.Time, error) {
+	return n.page.Date, nil
+}
+
+func (n *newsArticle) Authors() ([]string, error) {
+	return n.page.Authors, nil
+}
@@ -0,0 +1,85 @@
+package source
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestNews(t *testing.T) {
+	server := httptest.NewServer(nil)
+	defer server.Close()
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveFixture(w, "news"+r.URL.Path, server.URL)
+	})
+
+	urlFile, err := ioutil.TempFile("", "news_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(urlFile.Name())
+	urlFile.WriteString(server.URL + "/meta.html\n\n" + server.URL + "/jsonld.html\n")
+	urlFile.Close()
+
+	setEnv(t, map[string]string{
+		"NEWS_URLS":     server.URL + "/sitemap_index.xml",
+		"NEWS_URL_FILE": urlFile.Name(),
+	})
+
+	authors := collectAuthors(t, News{})
+	if len(authors) != 2 || authors[0].Name() != "Jane Doe" ||
+		authors[1].Name() != "John Roe" {
+		t.Fatal("unexpected authors:", authors)
+	}
+
+	// The co-authored article is listed under both authors.
+	expected := [][]string{
+		{
+			"The opening paragraph, which has plenty of words, commas, and clauses.\n\n" +
+				"A second paragraph, also fairly long, to anchor the body here.\n\nShort.",
+			"Written together.",
+		},
+		{
+			"Written together.",
+			"Only meta tags here, but the paragraph is long enough to count.",
+		},
+	}
+	expectedDays := [][]int{{20, 1}, {1, 19}}
+	expectedAuthors := [][]int{{1, 2}, {2, 1}}
+	for i, author := range authors {
+		arts := collectArticles(t, author)
+		if len(arts) != 2 {
+			t.Fatalf("author %d: expected 2 articles but got %d", i, len(arts))
+		}
+		for j, art := range arts {
+			body, _ := art.Body()
+			if body != expected[i][j] {
+				t.Errorf("author %d article %d: unexpected body %q", i, j, body)
+			}
+			if date, _ := art.Date(); date.Day() != expectedDays[i][j] {
+				t.Errorf("author %d article %d: unexpected date %v", i, j, date)
+			}
+			if names, _ := art.Authors(); len(names) != expectedAuthors[i][j] {
+				t.Errorf("author %d article %d: unexpected authors %v", i, j, names)
+			}
+		}
+	}
+
+	page, err := getHTML(nil, server.URL+"/jsonld.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if headline := extractPage(page).Headline; headline != "A JSON-LD article" {
+		t.Errorf("unexpected headline: %q", headline)
+	}
+
+	page, err = getHTML(nil, server.URL+"/coauthored.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := extractPage(page).Authors; len(names) != 2 {
+		t.Errorf("unexpected co-authors: %v", names)
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"encoding/hex"
 	"errors"
 	"net/http"
+	"net/url"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/yhat/scrape"
 
@@ -19,16 +22,60 @@ import (
 
 // NewYorker is a Source that fetches data from
 // The New Yorker (http://www.newyorker.com).
-type NewYorker struct{}
+type NewYorker struct {
+	// client makes the requests for the source, or is
+	// nil to use HTTPClient.
+	client *http.Client
+}
+
+const (
+	newYorkerContributors = "http://www.newyorker.com/contributors/"
+	newYorkerSitemap      = "http://www.newyorker.com/sitemap.xml"
+)
+
+var (
+	newYorkerProfile = regexp.MustCompile(`^https?://(www\.)?newyorker\.com/contributors/` +
+		`[a-z0-9-]+/?$`)
+	newYorkerListing = regexp.MustCompile(`^https?://(www\.)?newyorker\.com/contributors` +
+		`(/?|/page/\d+/?|/[a-z]/?)(\?page=\d+)?$`)
+)
 
 // Help returns the usage information for this Source.
 func (_ NewYorker) Help() string {
-	return "Fetch articles from NewYorker.\nNo flags at this time."
+	return "Fetch articles from NewYorker.\n" +
+		"Environment variables:\n" +
+		" NEWYORKER_SITEMAP          sitemap or sitemap index listing contributor pages\n" +
+		"                            (default " + newYorkerSitemap + ";\n" +
+		"                            set to an empty string to skip the sitemap)\n" +
+		" NEWYORKER_SECTIONS         comma-separated URL sections to include\n" +
+		"                            (default news,magazine; empty for all)\n" +
+		" NEWYORKER_EXCLUDE_SECTIONS comma-separated URL sections to exclude\n" +
+		" NEWYORKER_TYPES            comma-separated article types to include\n" +
+		"                            (default all)\n" +
+		" NEWYORKER_EXCLUDE_TYPES    comma-separated article types to exclude\n" +
+		" NEWYORKER_AFTER            only include articles published on or after this date\n" +
+		" NEWYORKER_BEFORE           only include articles published before this date\n" +
+		" NEWYORKER_MIN_WORDS        minimum number of words in an article (default 0)\n" +
+		"Article types are " + strings.Join(newYorkerTypes, ", ") + ".\n" +
+		"Dates are formatted like 2006-01-02.\n" +
+		"The type, date and word filters need each article's page, so they are\n" +
+		"applied when the article is fetched rather than when it is listed."
 }
 
-// Authors lists the contributors from the page:
-// http://www.newyorker.com/contributors/.
-func (_ NewYorker) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+// withClient returns a copy of the source which makes its
+// requests with client.
+func (n NewYorker) withClient(client *http.Client) Source {
+	n.client = client
+	return n
+}
+
+// Authors lists the contributors from the page
+// http://www.newyorker.com/contributors/, following its
+// paginated and alphabetical listings, and then adds any
+// other contributors found in the site's sitemap.
+//
+// Contributors are deduplicated by their profile URL.
+func (n NewYorker) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
 	authChan := make(chan Author, 1)
 	errChan := make(chan error, 1)
 
@@ -36,30 +83,81 @@ func (_ NewYorker) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
 		defer close(authChan)
 		defer close(errChan)
 
-		resp, err := http.Get("http://www.newyorker.com/contributors/")
-		if err != nil {
-			errChan <- err
-			return
+		seen := map[string]bool{}
+		send := func(a *newYorkerAuthor) bool {
+			key := newYorkerCanonical(a.url)
+			if seen[key] {
+				return true
+			}
+			seen[key] = true
+			select {
+			case <-stop:
+				return false
+			case authChan <- a:
+				return true
+			}
 		}
 
-		parsed, err := html.Parse(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			errChan <- err
-			return
+		visited := map[string]bool{}
+		queue := []string{newYorkerContributors}
+		for len(queue) > 0 {
+			u := queue[0]
+			queue = queue[1:]
+			if visited[newYorkerCanonical(u)] {
+				continue
+			}
+			visited[newYorkerCanonical(u)] = true
+
+			parsed, err := getHTML(n.client, u)
+			if isNotFound(err) && u != newYorkerContributors {
+				// A linked listing may be missing from an archive.
+				continue
+			} else if err != nil {
+				errChan <- err
+				return
+			}
+			authors, err := n.pageAuthors(parsed)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			for _, x := range authors {
+				x.url = strings.TrimSuffix(resolveURL(u, x.url), "/")
+				x.client = n.client
+				if !send(x) {
+					return
+				}
+			}
+			queue = append(queue, newYorkerListingLinks(parsed, u)...)
 		}
 
-		authors, err := NewYorker{}.pageAuthors(parsed)
+		sitemap, ok := os.LookupEnv("NEWYORKER_SITEMAP")
+		if !ok {
+			sitemap = newYorkerSitemap
+		}
+		if sitemap == "" {
+			return
+		}
+		profiles, err := newYorkerSitemapProfiles(n.client, sitemap)
 		if err != nil {
 			errChan <- err
 			return
 		}
-
-		for _, x := range authors {
-			select {
-			case <-stop:
+		for _, u := range profiles {
+			if seen[newYorkerCanonical(u)] {
+				continue
+			}
+			name, err := newYorkerProfileName(n.client, u)
+			if isNotFound(err) || (err == nil && name == "") {
+				continue
+			} else if err != nil {
+				errChan <- err
+				return
+			}
+			author := &newYorkerAuthor{name: name, url: strings.TrimSuffix(u, "/"),
+				client: n.client}
+			if !send(author) {
 				return
-			case authChan <- x:
 			}
 		}
 	}()
@@ -67,10 +165,20 @@ func (_ NewYorker) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
 	return authChan, errChan
 }
 
+// RestoreAuthor recreates a contributor from their name
+// and profile URL, which is the key of a listed author.
+func (n NewYorker) RestoreAuthor(name, key string) (Author, error) {
+	if !newYorkerProfile.MatchString(key) {
+		return nil, errors.New("invalid NewYorker author key: " + key)
+	}
+	return &newYorkerAuthor{name: name, url: strings.TrimSuffix(key, "/"), client: n.client}, nil
+}
+
 func (_ NewYorker) pageAuthors(page *html.Node) ([]*newYorkerAuthor, error) {
 	// Use a map to remove duplicates, since some authors are
 	// listed on the page twice.
-	res := map[string]*newYorkerAuthor{}
+	seen := map[string]bool{}
+	var list []*newYorkerAuthor
 
 	items := scrape.FindAll(page, func(n *html.Node) bool {
 		return scrape.Attr(n, "itemtype") == "http://schema.org/Person"
@@ -85,26 +193,133 @@ func (_ NewYorker) pageAuthors(page *html.Node) ([]*newYorkerAuthor, error) {
 		if name == "" {
 			return nil, errors.New("no name for person object")
 		}
-		res[u] = &newYorkerAuthor{name: name, url: u}
+		if !seen[u] {
+			seen[u] = true
+			list = append(list, &newYorkerAuthor{name: name, url: u})
+		}
 	}
 
-	list := make([]*newYorkerAuthor, 0, len(res))
-	for _, x := range res {
-		list = append(list, x)
-	}
 	return list, nil
 }
 
+// newYorkerListingLinks finds the links from a contributor
+// listing to its other pages and letters.
+//
+// Only numbered pages and letters are followed, so that
+// sorted or filtered views of the same listing (and links
+// to other sites, even with rel="next") are not crawled.
+func newYorkerListingLinks(page *html.Node, pageURL string) []string {
+	var res []string
+	for _, link := range scrape.FindAll(page, scrape.ByTag(atom.A)) {
+		href := scrape.Attr(link, "href")
+		if href == "" {
+			continue
+		}
+		if u := resolveURL(pageURL, href); newYorkerListing.MatchString(u) {
+			res = append(res, u)
+		}
+	}
+	return res
+}
+
+// newYorkerSitemapProfiles lists the contributor profile
+// URLs in a sitemap.
+//
+// In a sitemap index, only the sitemaps with "contributor"
+// in their URL are read, since the others list articles.
+// A missing sitemap produces no URLs.
+func newYorkerSitemapProfiles(client *http.Client, u string) ([]string, error) {
+	urls, sitemaps, err := readSitemap(client, u)
+	if isNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for _, page := range urls {
+		if newYorkerProfile.MatchString(page) {
+			res = append(res, page)
+		}
+	}
+	for _, sub := range sitemaps {
+		if !strings.Contains(strings.ToLower(sub), "contributor") {
+			continue
+		}
+		subURLs, err := newYorkerSitemapProfiles(client, sub)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, subURLs...)
+	}
+	return res, nil
+}
+
+// newYorkerProfileName reads a contributor's name from
+// their profile page.
+func newYorkerProfileName(client *http.Client, u string) (string, error) {
+	parsed, err := getHTML(client, u)
+	if err != nil {
+		return "", err
+	}
+	if h1, ok := scrape.Find(parsed, scrape.ByTag(atom.H1)); ok {
+		if name := nodeText(h1); name != "" {
+			return name, nil
+		}
+	}
+	if title, ok := scrape.Find(parsed, scrape.ByTag(atom.Title)); ok {
+		name := strings.SplitN(nodeText(title), "|", 2)[0]
+		return strings.TrimSpace(name), nil
+	}
+	return "", nil
+}
+
+// newYorkerHost checks if a host name belongs to the
+// NewYorker site.
+func newYorkerHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "newyorker.com" || strings.HasSuffix(host, ".newyorker.com")
+}
+
+// newYorkerCanonical normalizes a NewYorker URL so that
+// the same page is recognized regardless of its scheme,
+// "www." prefix or trailing slash.
+func newYorkerCanonical(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Host), "www.")
+	path := strings.TrimSuffix(parsed.Path, "/")
+	if parsed.RawQuery != "" {
+		path += "?" + parsed.RawQuery
+	}
+	return host + path
+}
+
 type newYorkerAuthor struct {
-	name string
-	url  string
+	name   string
+	url    string
+	client *http.Client
 }
 
 func (n *newYorkerAuthor) Name() string {
 	return n.name
 }
 
+// Key returns the contributor's profile URL.
+func (n *newYorkerAuthor) Key() string {
+	return n.url
+}
+
 func (n *newYorkerAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan error) {
+	return n.ArticlesFrom(1, stop)
+}
+
+// ArticlesFrom lists articles starting at a page of the
+// contributor's "all" listing.
+func (n *newYorkerAuthor) ArticlesFrom(page int, stop <-chan struct{}) (<-chan Article,
+	<-chan error) {
 	artChan := make(chan Article, 1)
 	errChan := make(chan error, 1)
 
@@ -112,11 +327,13 @@ func (n *newYorkerAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan
 		defer close(artChan)
 		defer close(errChan)
 
-		// filter out certain types of articles, like podcasts.
-		whitelist := regexp.MustCompile(`^https?:\/\/www.newyorker.com\/(` +
-			`news|magazine)\/.*$`)
+		filter, err := newYorkerFilterFromEnv()
+		if err != nil {
+			errChan <- err
+			return
+		}
 
-		for idx := 1; true; idx++ {
+		for idx := page; true; idx++ {
 			select {
 			case <-stop:
 				return
@@ -130,13 +347,15 @@ func (n *newYorkerAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan
 			}
 
 			for _, u := range urls {
-				if !whitelist.MatchString(u) {
+				if !filter.matchesURL(u) {
 					continue
 				}
+				art := &newYorkerArticle{url: u, author: n.name, listingPage: idx,
+					filter: filter, client: n.client}
 				select {
 				case <-stop:
 					return
-				case artChan <- &newYorkerArticle{url: u}:
+				case artChan <- art:
 				}
 			}
 
@@ -149,14 +368,117 @@ func (n *newYorkerAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan
 	return artChan, errChan
 }
 
-func (n *newYorkerAuthor) fetchPage(idx int) (urls []string, next bool, err error) {
-	resp, err := http.Get(n.url + "/all/" + strconv.Itoa(idx))
-	if err != nil {
-		return
+// newYorkerTypes lists the article types which can be
+// used in filters.
+var newYorkerTypes = []string{"article", "fiction", "poetry", "review", "interview"}
+
+// newYorkerTypeKeywords maps words found in an article's
+// section, tags or rubric to its type.
+// Earlier entries take precedence, so that a review of a
+// novel is not counted as fiction.
+var newYorkerTypeKeywords = []struct {
+	keyword string
+	artType string
+}{
+	{"interview", "interview"},
+	{"interviews", "interview"},
+	{"q. & a.", "interview"},
+	{"q&a", "interview"},
+	{"review", "review"},
+	{"reviews", "review"},
+	{"critic", "review"},
+	{"critics", "review"},
+	{"briefly noted", "review"},
+	{"poem", "poetry"},
+	{"poems", "poetry"},
+	{"poetry", "poetry"},
+	{"fiction", "fiction"},
+}
+
+// newYorkerFilter decides which articles to include.
+type newYorkerFilter struct {
+	sections        []string
+	excludeSections []string
+	types           []string
+	excludeTypes    []string
+	after           time.Time
+	before          time.Time
+	minWords        int
+}
+
+func newYorkerFilterFromEnv() (*newYorkerFilter, error) {
+	res := &newYorkerFilter{
+		sections:        envList("NEWYORKER_SECTIONS", []string{"news", "magazine"}),
+		excludeSections: envList("NEWYORKER_EXCLUDE_SECTIONS", nil),
+		types:           envList("NEWYORKER_TYPES", nil),
+		excludeTypes:    envList("NEWYORKER_EXCLUDE_TYPES", nil),
+	}
+	for _, t := range append(append([]string{}, res.types...), res.excludeTypes...) {
+		if !containsString(newYorkerTypes, t) {
+			return nil, errors.New("unknown NewYorker article type: " + t)
+		}
+	}
+	var err error
+	if res.after, err = envDate("NEWYORKER_AFTER"); err != nil {
+		return nil, err
+	}
+	if res.before, err = envDate("NEWYORKER_BEFORE"); err != nil {
+		return nil, err
 	}
+	if res.minWords, err = envInt("NEWYORKER_MIN_WORDS", 0); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
 
-	parsed, err := html.Parse(resp.Body)
-	resp.Body.Close()
+// matchesURL checks the section of an article URL, which
+// is the first component of its path.
+func (f *newYorkerFilter) matchesURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil || !newYorkerHost(parsed.Hostname()) {
+		return false
+	}
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(parts) < 2 {
+		// Section index pages are not articles.
+		return false
+	}
+	section := strings.ToLower(parts[0])
+	if len(f.sections) > 0 && !containsString(f.sections, section) {
+		return false
+	}
+	return !containsString(f.excludeSections, section)
+}
+
+// matchesArticle checks the filters which need the
+// article's page, given the article's document.
+//
+// It is only called once the article is fetched, so that
+// articles which a crawl has already handled are not
+// downloaded again just to be listed.
+func (f *newYorkerFilter) matchesArticle(a *newYorkerArticle, doc *Document) bool {
+	if len(f.types) > 0 || len(f.excludeTypes) > 0 {
+		artType := a.articleType()
+		if len(f.types) > 0 && !containsString(f.types, artType) ||
+			containsString(f.excludeTypes, artType) {
+			return false
+		}
+	}
+	if !f.after.IsZero() || !f.before.IsZero() {
+		date, err := a.Date()
+		if err != nil || date.Before(f.after) ||
+			!f.before.IsZero() && !date.Before(f.before) {
+			return false
+		}
+	}
+	if f.minWords > 0 && len(strings.Fields(doc.Text())) < f.minWords {
+		return false
+	}
+	return true
+}
+
+func (n *newYorkerAuthor) fetchPage(idx int) (urls []string, next bool, err error) {
+	parsed, err := getHTML(n.client, n.url+"/all/"+strconv.Itoa(idx))
 	if err != nil {
 		return
 	}
@@ -189,7 +511,11 @@ func (n *newYorkerAuthor) fetchPage(idx int) (urls []string, next bool, err erro
 }
 
 type newYorkerArticle struct {
-	url string
+	url         string
+	author      string
+	listingPage int
+	filter      *newYorkerFilter
+	client      *http.Client
 
 	pageLock sync.RWMutex
 	page     *html.Node
@@ -200,28 +526,42 @@ func (n *newYorkerArticle) ID() string {
 	return strings.ToLower(hex.EncodeToString(hash[:]))
 }
 
-func (n *newYorkerArticle) Body() (body string, err error) {
+func (n *newYorkerArticle) URL() string {
+	return n.url
+}
+
+func (n *newYorkerArticle) Body() (string, error) {
+	doc, err := n.Document()
+	if err != nil {
+		return "", err
+	}
+	return doc.Text(), nil
+}
+
+func (n *newYorkerArticle) Document() (doc *Document, err error) {
 	err = n.withPage(func() error {
 		artBody, ok := scrape.Find(n.page, scrape.ById("articleBody"))
 		if !ok {
 			return errors.New("no articleBody element")
 		}
-		paragraphs := scrape.FindAll(artBody, scrape.ByTag(atom.P))
-		var paraText []string
-		for _, p := range paragraphs {
-			paraText = append(paraText, strings.TrimSpace(scrape.Text(p)))
-		}
-		body = strings.Join(paraText, "\n\n")
+		doc = paragraphDocument(artBody, scrapeTextBlock)
 
-		if strings.HasPrefix(body, "This article is available to subscribers only") {
-			return errors.New("article is subscriber-only")
+		if strings.HasPrefix(doc.Text(), "This article is available to subscribers only") {
+			return ErrSubscriberOnly
 		}
 
 		return nil
 	})
+	if err == nil && n.filter != nil && !n.filter.matchesArticle(n, doc) {
+		return nil, ErrExcluded
+	}
 	return
 }
 
+func (n *newYorkerArticle) ListingPage() int {
+	return n.listingPage
+}
+
 func (n *newYorkerArticle) Date() (t time.Time, err error) {
 	err = n.withPage(func() error {
 		meta, ok := scrape.Find(n.page, func(n *html.Node) bool {
@@ -237,6 +577,102 @@ func (n *newYorkerArticle) Date() (t time.Time, err error) {
 	return
 }
 
+// Authors reads the byline of the article page.
+// If the page has no byline, the article is attributed to
+// the contributor it was listed under.
+func (n *newYorkerArticle) Authors() (names []string, err error) {
+	err = n.withPage(func() error {
+		bylines := scrape.FindAll(n.page, func(n *html.Node) bool {
+			return scrape.Attr(n, "itemprop") == "author" ||
+				scrape.Attr(n, "rel") == "author"
+		})
+		for _, byline := range bylines {
+			if name, ok := scrape.Find(byline, func(n *html.Node) bool {
+				return scrape.Attr(n, "itemprop") == "name"
+			}); ok {
+				byline = name
+			}
+			names = append(names, splitByline(nodeText(byline))...)
+		}
+		if len(names) == 0 {
+			if meta, ok := scrape.Find(n.page, func(n *html.Node) bool {
+				return n.DataAtom == atom.Meta && scrape.Attr(n, "name") == "author"
+			}); ok {
+				names = splitByline(scrape.Attr(meta, "content"))
+			}
+		}
+		names = uniqueStrings(names)
+		if len(names) == 0 {
+			names = []string{n.author}
+		}
+		return nil
+	})
+	return
+}
+
+// articleType classifies the article as one of
+// newYorkerTypes, based on its section, tags and rubric.
+//
+// Keywords must match whole words, so that a tag like
+// "Nonfiction" or "Science-Fiction" does not count as
+// fiction.
+// The URL is not used, since its slug is often taken from
+// the headline.
+func (n *newYorkerArticle) articleType() (artType string) {
+	n.withPage(func() error {
+		var signals []string
+		metas := scrape.FindAll(n.page, func(n *html.Node) bool {
+			prop := scrape.Attr(n, "property")
+			return n.DataAtom == atom.Meta &&
+				(prop == "article:section" || prop == "article:tag")
+		})
+		for _, meta := range metas {
+			signals = append(signals, scrape.Attr(meta, "content"))
+		}
+		for _, rubric := range scrape.FindAll(n.page, scrape.ByClass("rubric")) {
+			signals = append(signals, nodeText(rubric))
+		}
+		for _, k := range newYorkerTypeKeywords {
+			keyword := newYorkerWords(k.keyword)
+			for _, signal := range signals {
+				if containsWords(newYorkerWords(signal), keyword) {
+					artType = k.artType
+					return nil
+				}
+			}
+		}
+		artType = "article"
+		return nil
+	})
+	return
+}
+
+// newYorkerWords splits a tag or rubric into lowercase
+// words, keeping hyphenated words and "&" together.
+func newYorkerWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '&'
+	})
+}
+
+// containsWords checks if a sequence of words appears in
+// a list of words.
+func containsWords(words, seq []string) bool {
+	for i := 0; i+len(seq) <= len(words); i++ {
+		match := true
+		for j, w := range seq {
+			if words[i+j] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
 func (n *newYorkerArticle) withPage(f func() error) error {
 	n.pageLock.RLock()
 	if n.page == nil {
@@ -258,13 +694,7 @@ func (n *newYorkerArticle) fetchPage() error {
 		return nil
 	}
 
-	resp, err := http.Get(n.url)
-	if err != nil {
-		return err
-	}
-
-	n.page, err = html.Parse(resp.Body)
-	resp.Body.Close()
-
+	var err error
+	n.page, err = getHTML(n.client, n.url)
 	return err
 }
@@ -2,7 +2,16 @@ package source
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"golang.org/x/net/html"
 )
 
 func TestNewYorker(t *testing.T) {
@@ -40,3 +49,268 @@ func TestNewYorker(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestNewYorkerContributors(t *testing.T) {
+	serveNewYorker(t, map[string]string{
+		"/contributors":             "contributors.html",
+		"/contributors?page=2":      "contributors-page-2.html",
+		"/contributors/r":           "contributors-r.html",
+		"/sitemap.xml":              "sitemap.xml",
+		"/sitemap-contributors.xml": "sitemap-contributors.xml",
+		"/contributors/mary-major":  "mary-major.html",
+	})
+
+	authors := collectAuthors(t, NewYorker{})
+	var names, urls []string
+	for _, a := range authors {
+		names = append(names, a.Name())
+		urls = append(urls, a.(*newYorkerAuthor).url)
+	}
+	if strings.Join(names, ",") != "Jane Doe,John Roe,Mary Major" {
+		t.Error("unexpected authors:", names)
+	}
+	expectedURLs := []string{
+		"http://www.newyorker.com/contributors/jane-doe",
+		"http://www.newyorker.com/contributors/john-roe",
+		"https://www.newyorker.com/contributors/mary-major",
+	}
+	if !reflect.DeepEqual(urls, expectedURLs) {
+		t.Error("unexpected URLs:", urls)
+	}
+	for _, a := range authors {
+		restored, err := NewYorker{}.RestoreAuthor(a.Name(), a.(KeyedAuthor).Key())
+		if err != nil {
+			t.Error(err)
+		} else if !reflect.DeepEqual(restored, a) {
+			t.Errorf("restored %+v as %+v", a, restored)
+		}
+	}
+	if _, err := (NewYorker{}).RestoreAuthor("Jane Doe", "http://example.com/"); err == nil {
+		t.Error("expected error for invalid key")
+	}
+
+	setEnv(t, map[string]string{"NEWYORKER_SITEMAP": ""})
+	if authors := collectAuthors(t, NewYorker{}); len(authors) != 2 {
+		t.Error("expected 2 authors without sitemap but got", len(authors))
+	}
+}
+
+func TestNewYorkerListingLinks(t *testing.T) {
+	page, err := html.Parse(strings.NewReader(`<html><body>
+<a href="/contributors/r">R</a>
+<a href="/contributors/r?page=2">R, page 2</a>
+<a href="/contributors?page=2" rel="next">Next</a>
+<a href="https://www.newyorker.com/contributors/page/3/">Page 3</a>
+<a href="/contributors?sort=name">By name</a>
+<a href="/contributors?page=2&amp;sort=name">By name, page 2</a>
+<a href="http://example.com/contributors?page=2" rel="next">Elsewhere</a>
+<a href="http://evilnewyorker.com/contributors?page=2">Lookalike</a>
+<a href="/contributors/jane-doe">Jane Doe</a>
+</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := newYorkerListingLinks(page, "http://www.newyorker.com/contributors")
+	expected := []string{
+		"http://www.newyorker.com/contributors/r",
+		"http://www.newyorker.com/contributors/r?page=2",
+		"http://www.newyorker.com/contributors?page=2",
+		"https://www.newyorker.com/contributors/page/3/",
+	}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("unexpected links: %v", links)
+	}
+}
+
+func TestNewYorkerFilter(t *testing.T) {
+	serveNewYorker(t, map[string]string{
+		"/contributors/jane-doe/all/1":                 "filter-listing.html",
+		"/magazine/2016/11/21/a-long-piece":            "a-long-piece.html",
+		"/magazine/2016/11/28/a-poem":                  "a-poem.html",
+		"/news/daily-comment/2015/01/05/a-short-piece": "a-short-piece.html",
+	})
+	author := &newYorkerAuthor{name: "Jane Doe", url: "http://www.newyorker.com/contributors/jane-doe"}
+
+	tests := []struct {
+		env      map[string]string
+		expected []string
+	}{
+		{
+			map[string]string{},
+			[]string{"a-long-piece", "a-poem", "a-short-piece"},
+		},
+		{
+			map[string]string{"NEWYORKER_SECTIONS": "news,culture"},
+			[]string{"a-short-piece"},
+		},
+		{
+			map[string]string{"NEWYORKER_EXCLUDE_SECTIONS": "news"},
+			[]string{"a-long-piece", "a-poem"},
+		},
+		{
+			map[string]string{"NEWYORKER_TYPES": "poetry"},
+			[]string{"a-poem"},
+		},
+		{
+			map[string]string{"NEWYORKER_EXCLUDE_TYPES": "poetry,fiction"},
+			[]string{"a-long-piece", "a-short-piece"},
+		},
+		{
+			map[string]string{"NEWYORKER_AFTER": "2016-01-01", "NEWYORKER_BEFORE": "2016-11-25"},
+			[]string{"a-long-piece"},
+		},
+		{
+			map[string]string{"NEWYORKER_MIN_WORDS": "8"},
+			[]string{"a-long-piece", "a-poem"},
+		},
+	}
+	vars := []string{"NEWYORKER_SECTIONS", "NEWYORKER_EXCLUDE_SECTIONS", "NEWYORKER_TYPES",
+		"NEWYORKER_EXCLUDE_TYPES", "NEWYORKER_AFTER", "NEWYORKER_BEFORE", "NEWYORKER_MIN_WORDS"}
+	for i, test := range tests {
+		for _, name := range vars {
+			os.Unsetenv(name)
+		}
+		setEnv(t, test.env)
+		var slugs []string
+		for _, art := range collectArticles(t, author) {
+			if art.(*newYorkerArticle).page != nil {
+				t.Errorf("test %d: article page fetched while listing", i)
+			}
+			if _, err := art.Document(); err == ErrExcluded {
+				continue
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			slugs = append(slugs, path.Base(art.(*newYorkerArticle).url))
+		}
+		if !reflect.DeepEqual(slugs, test.expected) {
+			t.Errorf("test %d: expected %v but got %v", i, test.expected, slugs)
+		}
+	}
+
+	setEnv(t, map[string]string{"NEWYORKER_TYPES": "podcast"})
+	_, errChan := author.Articles(nil)
+	if err := <-errChan; err == nil {
+		t.Error("expected error for unknown type")
+	}
+}
+
+func TestNewYorkerMatchesURL(t *testing.T) {
+	filter := &newYorkerFilter{}
+	tests := map[string]bool{
+		"http://www.newyorker.com/magazine/2016/11/21/a-long-piece": true,
+		"https://newyorker.com/news/daily-comment/a-short-piece":    true,
+		"https://WWW.NEWYORKER.COM:443/culture/a-review":            true,
+		"http://evilnewyorker.com/magazine/2016/11/21/a-long-piece": false,
+		"http://newyorker.com.example.com/magazine/a-long-piece":    false,
+		"http://www.newyorker.com/magazine":                         false,
+	}
+	for u, expected := range tests {
+		if filter.matchesURL(u) != expected {
+			t.Errorf("%s: expected %v", u, expected)
+		}
+	}
+}
+
+func TestNewYorkerBody(t *testing.T) {
+	serveNewYorker(t, map[string]string{
+		"/magazine/2016/12/05/a-structured-piece": "a-structured-piece.html",
+	})
+	art := &newYorkerArticle{url: "http://www.newyorker.com/magazine/2016/12/05/a-structured-piece"}
+
+	// This is the text that was read from the page before
+	// articles had Documents, which should not change.
+	expected := "Some bold . text here.\n\nA quoted paragraph.\n\nLast."
+	body, err := art.Body()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != expected {
+		t.Errorf("unexpected body %q", body)
+	}
+
+	doc, err := art.Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedBlocks := []Block{
+		{Type: Prose, Text: "Some bold . text here.", Emphasis: []Span{{Start: 5, End: 9}}},
+		{Type: Quote, Text: "A quoted paragraph."},
+		{Type: Prose, Text: "Last."},
+	}
+	if !reflect.DeepEqual(doc.Blocks, expectedBlocks) {
+		t.Errorf("unexpected blocks %+v", doc.Blocks)
+	}
+}
+
+func TestNewYorkerArticleType(t *testing.T) {
+	tests := []struct {
+		url      string
+		meta     []string
+		rubric   string
+		expected string
+	}{
+		{"/magazine/2016/11/28/a-poem", []string{"Poems"}, "", "poetry"},
+		{"/magazine/2016/11/28/a-story", []string{"Fiction"}, "", "fiction"},
+		{"/magazine/2016/11/28/a-story", nil, "Fiction", "fiction"},
+		{"/magazine/2016/11/28/a-memoir", []string{"Books", "Nonfiction"}, "Books", "article"},
+		{"/magazine/2016/11/28/a-novel", []string{"Science-Fiction", "Book Reviews"}, "",
+			"review"},
+		{"/magazine/2016/11/28/a-novel", []string{"Fiction"}, "Briefly Noted", "review"},
+		{"/news/daily-comment/2016/11/28/the-poem-review", []string{"Politics"}, "", "article"},
+		{"/culture/2016/11/28/talking", nil, "Q. & A.", "interview"},
+		{"/culture/2016/11/28/previews", []string{"Previews"}, "", "article"},
+	}
+	for i, test := range tests {
+		page := "<html><head>"
+		for j, content := range test.meta {
+			prop := "article:tag"
+			if j == 0 {
+				prop = "article:section"
+			}
+			page += `<meta property="` + prop + `" content="` + content + `">`
+		}
+		page += `</head><body><p class="rubric">` + test.rubric + `</p></body></html>`
+		parsed, err := html.Parse(strings.NewReader(page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		art := &newYorkerArticle{url: "http://www.newyorker.com" + test.url, page: parsed}
+		if actual := art.articleType(); actual != test.expected {
+			t.Errorf("test %d: expected %s but got %s", i, test.expected, actual)
+		}
+	}
+}
+
+// serveNewYorker replaces HTTPClient for the duration of a
+// test, serving fixtures from testdata/newyorker for the
+// given paths (with any query string) and 404 errors for
+// everything else.
+func serveNewYorker(t *testing.T, fixtures map[string]string) {
+	oldClient := HTTPClient
+	t.Cleanup(func() {
+		HTTPClient = oldClient
+	})
+	HTTPClient = &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		key := strings.TrimSuffix(r.URL.Path, "/")
+		if r.URL.RawQuery != "" {
+			key += "?" + r.URL.RawQuery
+		}
+		if key == "/sitemap-articles.xml" {
+			t.Error("unexpected request for article sitemap")
+		}
+		rec := httptest.NewRecorder()
+		if name, ok := fixtures[key]; ok {
+			serveFixture(rec, filepath.Join("newyorker", name), "")
+		} else {
+			rec.WriteHeader(http.StatusNotFound)
+		}
+		return rec.Result(), nil
+	})}
+}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (r roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
@@ -0,0 +1,344 @@
+package source
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yhat/scrape"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	nytDefaultAPIURL = "https://api.nytimes.com/svc/search/v2/articlesearch.json"
+
+	// The search API refuses to page past this point.
+	nytMaxPages = 100
+)
+
+// NYTimes is a Source that fetches data from
+// The New York Times (http://www.nytimes.com).
+//
+// Authors and articles are discovered through the
+// Article Search API, while article bodies are scraped
+// from the article pages themselves.
+type NYTimes struct {
+	// client makes the requests for the source, or is
+	// nil to use HTTPClient.
+	client *http.Client
+}
+
+// Help returns the usage information for this Source.
+func (_ NYTimes) Help() string {
+	return "Fetch articles from The New York Times.\n" +
+		"Environment variables:\n" +
+		" NYT_API_KEY   Article Search API key (required)\n" +
+		" NYT_QUERY     search query used to discover authors\n" +
+		" NYT_PAGES     result pages to scan for authors (default 10)\n" +
+		" NYT_DELAY     delay between API calls (default 6s)\n" +
+		" NYT_API_URL   override the Article Search endpoint"
+}
+
+// withClient returns a copy of the source which makes its
+// requests with client.
+func (n NYTimes) withClient(client *http.Client) Source {
+	n.client = client
+	return n
+}
+
+// Authors scans recent search results for bylined
+// authors, producing each person the first time they
+// are seen.
+func (n NYTimes) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+	authChan := make(chan Author, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(authChan)
+		defer close(errChan)
+
+		api, err := newNYTAPI(n.client)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		numPages, err := envInt("NYT_PAGES", 10)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		seen := map[string]bool{}
+		query := url.Values{}
+		if q := os.Getenv("NYT_QUERY"); q != "" {
+			query.Set("q", q)
+		}
+		err = api.search(query, numPages, stop, func(doc *nytDoc) bool {
+			for _, name := range doc.authorNames() {
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				select {
+				case <-stop:
+					return false
+				case authChan <- &nytAuthor{api: api, name: name}:
+				}
+			}
+			return true
+		})
+		if err != nil {
+			errChan <- err
+		}
+	}()
+
+	return authChan, errChan
+}
+
+type nytAPI struct {
+	url    string
+	key    string
+	delay  time.Duration
+	client *http.Client
+
+	lock     sync.Mutex
+	lastCall time.Time
+}
+
+func newNYTAPI(client *http.Client) (*nytAPI, error) {
+	res := &nytAPI{
+		url:    os.Getenv("NYT_API_URL"),
+		key:    os.Getenv("NYT_API_KEY"),
+		delay:  time.Second * 6,
+		client: client,
+	}
+	if res.url == "" {
+		res.url = nytDefaultAPIURL
+	}
+	if res.key == "" {
+		return nil, errors.New("missing NYT_API_KEY")
+	}
+	if s := os.Getenv("NYT_DELAY"); s != "" {
+		var err error
+		res.delay, err = time.ParseDuration(s)
+		if err != nil {
+			return nil, errors.New("invalid NYT_DELAY: " + s)
+		}
+	}
+	return res, nil
+}
+
+// search pages through the results for a query, calling
+// f for every document until f returns false, the stop
+// channel is closed, or maxPages pages have been read.
+func (n *nytAPI) search(query url.Values, maxPages int, stop <-chan struct{},
+	f func(doc *nytDoc) bool) error {
+	if maxPages > nytMaxPages {
+		maxPages = nytMaxPages
+	}
+	query.Set("api-key", n.key)
+	query.Set("sort", "newest")
+	for page := 0; page < maxPages; page++ {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		query.Set("page", strconv.Itoa(page))
+		var resp nytSearchResponse
+		if err := n.call(query, &resp); err != nil {
+			return err
+		}
+		for i := range resp.Response.Docs {
+			if !f(&resp.Response.Docs[i]) {
+				return nil
+			}
+		}
+		read := (page + 1) * 10
+		if len(resp.Response.Docs) == 0 || read >= resp.Response.Meta.Hits {
+			break
+		}
+	}
+	return nil
+}
+
+func (n *nytAPI) call(query url.Values, resp *nytSearchResponse) error {
+	n.lock.Lock()
+	if wait := n.delay - time.Since(n.lastCall); wait > 0 {
+		time.Sleep(wait)
+	}
+	n.lastCall = time.Now()
+	n.lock.Unlock()
+	return getJSON(n.client, n.url+"?"+query.Encode(), resp)
+}
+
+type nytSearchResponse struct {
+	Response struct {
+		Docs []nytDoc `json:"docs"`
+		Meta struct {
+			Hits int `json:"hits"`
+		} `json:"meta"`
+	} `json:"response"`
+}
+
+type nytDoc struct {
+	WebURL  string `json:"web_url"`
+	PubDate string `json:"pub_date"`
+	Byline  struct {
+		Original string `json:"original"`
+		Person   []struct {
+			FirstName  string `json:"firstname"`
+			MiddleName string `json:"middlename"`
+			LastName   string `json:"lastname"`
+			Role       string `json:"role"`
+		} `json:"person"`
+	} `json:"byline"`
+}
+
+// authorNames returns the names of everyone in the
+// byline, preferring the structured person list and
+// falling back to the raw byline text.
+func (n *nytDoc) authorNames() []string {
+	var res []string
+	for _, p := range n.Byline.Person {
+		if p.Role != "" && p.Role != "reported" {
+			continue
+		}
+		name := nytNormalizeName(p.FirstName + " " + p.MiddleName + " " + p.LastName)
+		if name != "" {
+			res = append(res, name)
+		}
+	}
+	if len(res) > 0 {
+		return res
+	}
+
+	byline := strings.TrimSpace(n.Byline.Original)
+	if !strings.HasPrefix(strings.ToLower(byline), "by ") {
+		return nil
+	}
+	for _, name := range splitByline(byline) {
+		res = append(res, nytNormalizeName(name))
+	}
+	return res
+}
+
+// nytNormalizeName collapses whitespace and fixes the
+// all-caps names that the API sometimes returns.
+func nytNormalizeName(name string) string {
+	fields := strings.Fields(name)
+	for i, f := range fields {
+		if f == strings.ToUpper(f) && utf8.RuneCountInString(f) > 1 {
+			_, size := utf8.DecodeRuneInString(f)
+			fields[i] = f[:size] + strings.ToLower(f[size:])
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+type nytAuthor struct {
+	api  *nytAPI
+	name string
+}
+
+func (n *nytAuthor) Name() string {
+	return n.name
+}
+
+func (n *nytAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan error) {
+	artChan := make(chan Article, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(artChan)
+		defer close(errChan)
+
+		query := url.Values{}
+		query.Set("fq", `byline:("`+n.name+`")`)
+		err := n.api.search(query, nytMaxPages, stop, func(doc *nytDoc) bool {
+			// The byline filter is fuzzy, so we make sure
+			// the author really wrote the piece.
+			names := doc.authorNames()
+			if !containsString(names, n.name) || doc.WebURL == "" {
+				return true
+			}
+			art := &nytArticle{url: doc.WebURL, date: parseDate(doc.PubDate), authors: names,
+				client: n.api.client}
+			select {
+			case <-stop:
+				return false
+			case artChan <- art:
+			}
+			return true
+		})
+		if err != nil {
+			errChan <- err
+		}
+	}()
+
+	return artChan, errChan
+}
+
+type nytArticle struct {
+	url     string
+	date    time.Time
+	authors []string
+	client  *http.Client
+}
+
+func (n *nytArticle) ID() string {
+	return hashID(n.url)
+}
+
+func (n *nytArticle) URL() string {
+	return n.url
+}
+
+func (n *nytArticle) Body() (string, error) {
+	doc, err := n.Document()
+	if err != nil {
+		return "", err
+	}
+	return doc.Text(), nil
+}
+
+func (n *nytArticle) Document() (*Document, error) {
+	page, err := getHTML(n.client, n.url)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	artBody, ok := scrape.Find(page, func(n *html.Node) bool {
+		return scrape.Attr(n, "name") == "articleBody" ||
+			scrape.Attr(n, "itemprop") == "articleBody"
+	})
+	if ok {
+		doc = paragraphDocument(artBody, htmlInlineBlock)
+	} else {
+		for _, p := range scrape.FindAll(page, scrape.ByClass("story-body-text")) {
+			doc.addBlock(htmlInlineBlock(p, Prose))
+		}
+	}
+	if len(doc.Blocks) == 0 {
+		return nil, errors.New("no article body found")
+	}
+	return doc, nil
+}
+
+func (n *nytArticle) Date() (time.Time, error) {
+	return n.date, nil
+}
+
+func (n *nytArticle) Authors() ([]string, error) {
+	return n.authors, nil
+}
@@ -0,0 +1,89 @@
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNYTimes(t *testing.T) {
+	server := httptest.NewServer(nil)
+	defer server.Close()
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/search" {
+			if r.URL.Query().Get("api-key") != "testkey" {
+				http.Error(w, "bad key", http.StatusUnauthorized)
+				return
+			}
+			serveFixture(w, "nytimes/search.json", server.URL)
+		} else {
+			serveFixture(w, "nytimes/"+filepath.Base(r.URL.Path), server.URL)
+		}
+	})
+
+	setEnv(t, map[string]string{
+		"NYT_API_URL": server.URL + "/search",
+		"NYT_API_KEY": "testkey",
+		"NYT_DELAY":   "0s",
+	})
+
+	authors := collectAuthors(t, NYTimes{})
+	var names []string
+	for _, a := range authors {
+		names = append(names, a.Name())
+	}
+	if strings.Join(names, ",") != "Jane Doe,John Roe,Mary Major" {
+		t.Fatal("unexpected authors:", names)
+	}
+
+	arts := collectArticles(t, authors[0])
+	if len(arts) != 3 {
+		t.Fatal("expected 3 articles but got", len(arts))
+	}
+	expectedAuthors := []string{"Jane Doe", "Jane Doe,John Roe", "Jane Doe,John Roe,Mary Major"}
+	for i, art := range arts {
+		if names, _ := art.Authors(); strings.Join(names, ",") != expectedAuthors[i] {
+			t.Errorf("article %d: unexpected authors %v", i, names)
+		}
+	}
+	body, err := arts[0].Body()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "The first paragraph of the article.\n\nThe second paragraph."
+	if body != expected {
+		t.Errorf("unexpected body: %q", body)
+	}
+	if date, _ := arts[0].Date(); date.Day() != 20 {
+		t.Errorf("unexpected date: %v", date)
+	}
+
+	if arts := collectArticles(t, authors[1]); len(arts) != 2 {
+		t.Errorf("expected 2 articles for co-author but got %d", len(arts))
+	}
+
+	legacy := &nytArticle{url: server.URL + "/legacy.html"}
+	body, err = legacy.Body()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "An older article layout.\n\nWith two paragraphs." {
+		t.Errorf("unexpected legacy body: %q", body)
+	}
+}
+
+func TestNYTNormalizeName(t *testing.T) {
+	tests := map[string]string{
+		"JOHN  SMITH":    "John Smith",
+		"ÉMILE ZOLA":     "Émile Zola",
+		"Jane O. Doe":    "Jane O. Doe",
+		" ÅSA  LARSSON ": "Åsa Larsson",
+	}
+	for name, expected := range tests {
+		if actual := nytNormalizeName(name); actual != expected {
+			t.Errorf("%q: expected %q but got %q", name, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,511 @@
+package source
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	documentsWordSpace = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	documentsODTSpace  = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+)
+
+// Documents is a Source that reads EPUB, DOCX and ODT
+// files from a directory tree.
+//
+// The author of each file is read from its metadata.
+// Files without an author in their metadata are attributed
+// to the top-level sub-directory containing them, in the
+// same way as model.ReadSamples.
+type Documents struct{}
+
+// Help returns the usage information for this Source.
+func (_ Documents) Help() string {
+	return "Read EPUB, DOCX and ODT documents from a directory.\n" +
+		"Environment variables:\n" +
+		" DOCUMENTS_DIR   directory to search recursively (required)\n" +
+		"Files without an author in their metadata are attributed to\n" +
+		"the sub-directory of DOCUMENTS_DIR which contains them."
+}
+
+// Authors reads the metadata of every document and then
+// produces the authors in alphabetical order.
+func (_ Documents) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+	authChan := make(chan Author, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(authChan)
+		defer close(errChan)
+
+		dir := os.Getenv("DOCUMENTS_DIR")
+		if dir == "" {
+			errChan <- errors.New("missing DOCUMENTS_DIR")
+			return
+		}
+
+		authors := map[string]*documentsAuthor{}
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if path == dir {
+					return err
+				}
+				log.Println("skipping unreadable path:", err)
+				return nil
+			}
+			if info.IsDir() || documentsFormat(path) == nil {
+				return nil
+			}
+			doc, err := documentsReadMeta(path)
+			if err != nil {
+				log.Println("skipping document " + path + ": " + err.Error())
+				return nil
+			}
+			if len(doc.authors) == 0 {
+				rel, _ := filepath.Rel(dir, path)
+				if parts := strings.Split(rel, string(filepath.Separator)); len(parts) > 1 {
+					doc.authors = []string{parts[0]}
+				}
+			}
+			if doc.date.IsZero() {
+				doc.date = info.ModTime()
+			}
+			for _, name := range doc.authors {
+				if authors[name] == nil {
+					authors[name] = &documentsAuthor{name: name}
+				}
+				authors[name].docs = append(authors[name].docs, doc)
+			}
+			return nil
+		})
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		var names []string
+		for name := range authors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			select {
+			case <-stop:
+				return
+			case authChan <- authors[name]:
+			}
+		}
+	}()
+
+	return authChan, errChan
+}
+
+// A documentsFormatReader reads the metadata and text of
+// one kind of zipped document.
+type documentsFormatReader struct {
+	meta func(z *zip.Reader) ([]string, time.Time, error)
+	text func(z *zip.Reader) (string, error)
+}
+
+func documentsFormat(path string) *documentsFormatReader {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".epub":
+		return &documentsFormatReader{meta: epubMeta, text: epubText}
+	case ".docx":
+		return &documentsFormatReader{meta: docxMeta, text: docxText}
+	case ".odt":
+		return &documentsFormatReader{meta: odtMeta, text: odtText}
+	}
+	return nil
+}
+
+func documentsReadMeta(path string) (*documentsArticle, error) {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	authors, date, err := documentsFormat(path).meta(&z.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &documentsArticle{path: path, authors: authors, date: date}, nil
+}
+
+// documentsAuthorNames cleans up a list of creators,
+// splitting entries like "Jane Doe; John Roe".
+func documentsAuthorNames(creators []string) []string {
+	var res []string
+	for _, c := range creators {
+		for _, name := range strings.Split(c, ";") {
+			if name = normalizeByline(name); name != "" {
+				res = append(res, name)
+			}
+		}
+	}
+	return uniqueStrings(res)
+}
+
+func documentsOpen(z *zip.Reader, name string) (io.ReadCloser, error) {
+	for _, f := range z.File {
+		if f.Name == name {
+			return f.Open()
+		}
+	}
+	return nil, errors.New("missing " + name)
+}
+
+func documentsHas(z *zip.Reader, name string) bool {
+	for _, f := range z.File {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func documentsDecode(z *zip.Reader, name string, v interface{}) error {
+	r, err := documentsOpen(z, name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := xml.NewDecoder(r).Decode(v); err != nil {
+		return errors.New(name + ": " + err.Error())
+	}
+	return nil
+}
+
+type epubPackage struct {
+	Creators []struct {
+		Role string `xml:"role,attr"`
+		Name string `xml:",chardata"`
+	} `xml:"metadata>creator"`
+	Date     string `xml:"metadata>date"`
+	Manifest []struct {
+		ID   string `xml:"id,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// epubPackagePath finds the OPF package document through
+// META-INF/container.xml.
+func epubPackagePath(z *zip.Reader) (string, error) {
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := documentsDecode(z, "META-INF/container.xml", &container); err != nil {
+		return "", err
+	}
+	if len(container.Rootfiles) == 0 {
+		return "", errors.New("no rootfile in container.xml")
+	}
+	return container.Rootfiles[0].FullPath, nil
+}
+
+func epubReadPackage(z *zip.Reader) (string, *epubPackage, error) {
+	opfPath, err := epubPackagePath(z)
+	if err != nil {
+		return "", nil, err
+	}
+	var pkg epubPackage
+	if err := documentsDecode(z, opfPath, &pkg); err != nil {
+		return "", nil, err
+	}
+	return opfPath, &pkg, nil
+}
+
+func epubMeta(z *zip.Reader) ([]string, time.Time, error) {
+	_, pkg, err := epubReadPackage(z)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var creators []string
+	for _, c := range pkg.Creators {
+		// Illustrators, editors and the like are also listed
+		// as creators, but with a different role.
+		if c.Role == "" || c.Role == "aut" {
+			creators = append(creators, c.Name)
+		}
+	}
+	return documentsAuthorNames(creators), parseDate(pkg.Date), nil
+}
+
+// epubText reads the XHTML documents in the spine in
+// reading order.
+func epubText(z *zip.Reader) (string, error) {
+	opfPath, pkg, err := epubReadPackage(z)
+	if err != nil {
+		return "", err
+	}
+	hrefs := map[string]string{}
+	for _, item := range pkg.Manifest {
+		// Manifest hrefs are URLs, so names with spaces or
+		// other special characters are escaped.
+		href, err := url.PathUnescape(item.Href)
+		if err != nil {
+			href = item.Href
+		}
+		hrefs[item.ID] = path.Join(path.Dir(opfPath), href)
+	}
+	var chapters []string
+	for _, ref := range pkg.Spine {
+		r, err := documentsOpen(z, hrefs[ref.IDRef])
+		if err != nil {
+			return "", err
+		}
+		data, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return "", err
+		}
+		if text := htmlText(string(data)); text != "" {
+			chapters = append(chapters, text)
+		}
+	}
+	return strings.Join(chapters, "\n\n"), nil
+}
+
+func docxMeta(z *zip.Reader) ([]string, time.Time, error) {
+	var core struct {
+		Creator string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		Created string `xml:"http://purl.org/dc/terms/ created"`
+	}
+	if !documentsHas(z, "docProps/core.xml") {
+		return nil, time.Time{}, nil
+	}
+	if err := documentsDecode(z, "docProps/core.xml", &core); err != nil {
+		return nil, time.Time{}, err
+	}
+	return documentsAuthorNames([]string{core.Creator}), parseDate(core.Created), nil
+}
+
+func docxText(z *zip.Reader) (string, error) {
+	return documentsXMLText(z, "word/document.xml", false, func(name xml.Name) documentsNode {
+		if name.Space != documentsWordSpace {
+			return documentsOther
+		}
+		switch name.Local {
+		case "p":
+			return documentsParagraph
+		case "t":
+			return documentsText
+		case "tab":
+			return documentsSpace
+		case "br", "cr":
+			return documentsNewline
+		}
+		return documentsOther
+	})
+}
+
+func odtMeta(z *zip.Reader) ([]string, time.Time, error) {
+	var meta struct {
+		InitialCreator string `xml:"meta>initial-creator"`
+		Creator        string `xml:"meta>creator"`
+		CreationDate   string `xml:"meta>creation-date"`
+	}
+	if !documentsHas(z, "meta.xml") {
+		return nil, time.Time{}, nil
+	}
+	if err := documentsDecode(z, "meta.xml", &meta); err != nil {
+		return nil, time.Time{}, err
+	}
+	creator := meta.InitialCreator
+	if creator == "" {
+		creator = meta.Creator
+	}
+	return documentsAuthorNames([]string{creator}), parseDate(meta.CreationDate), nil
+}
+
+func odtText(z *zip.Reader) (string, error) {
+	return documentsXMLText(z, "content.xml", true, func(name xml.Name) documentsNode {
+		if name.Space != documentsODTSpace {
+			return documentsOther
+		}
+		switch name.Local {
+		case "p", "h":
+			return documentsParagraph
+		case "s", "tab":
+			return documentsSpace
+		case "line-break":
+			return documentsNewline
+		case "note", "tracked-changes":
+			return documentsSkip
+		}
+		return documentsOther
+	})
+}
+
+type documentsNode int
+
+const (
+	documentsOther documentsNode = iota
+	documentsParagraph
+	documentsText
+	documentsSpace
+	documentsNewline
+	documentsSkip
+)
+
+// documentsXMLText extracts paragraphs from an XML file,
+// using classify to decide how each element is treated.
+// Character data is kept inside text elements and, if
+// paraText is set, anywhere inside paragraphs.
+func documentsXMLText(z *zip.Reader, name string, paraText bool,
+	classify func(xml.Name) documentsNode) (string, error) {
+	r, err := documentsOpen(z, name)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	var paragraphs []string
+	var cur []byte
+	var stack []documentsNode
+	inText := func() bool {
+		var text bool
+		for _, n := range stack {
+			if n == documentsSkip {
+				return false
+			}
+			text = text || n == documentsText || (paraText && n == documentsParagraph)
+		}
+		return text
+	}
+
+	dec := xml.NewDecoder(r)
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", errors.New(name + ": " + err.Error())
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			kind := classify(token.Name)
+			switch kind {
+			case documentsSpace:
+				cur = append(cur, ' ')
+			case documentsNewline:
+				cur = append(cur, '\n')
+			}
+			stack = append(stack, kind)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				break
+			}
+			kind := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if kind == documentsParagraph {
+				var lines []string
+				for _, line := range strings.Split(string(cur), "\n") {
+					lines = append(lines, strings.TrimSpace(line))
+				}
+				if text := strings.TrimSpace(strings.Join(lines, "\n")); text != "" {
+					paragraphs = append(paragraphs, text)
+				}
+				cur = nil
+			}
+		case xml.CharData:
+			if inText() {
+				cur = append(cur, documentsSpaceRun.ReplaceAllString(string(token), " ")...)
+			}
+		}
+	}
+	return strings.Join(paragraphs, "\n\n"), nil
+}
+
+// documentsSpaceRun matches the runs of whitespace which
+// word processors display as a single space.
+var documentsSpaceRun = regexp.MustCompile(`\s+`)
+
+type documentsAuthor struct {
+	name string
+	docs []*documentsArticle
+}
+
+func (d *documentsAuthor) Name() string {
+	return d.name
+}
+
+func (d *documentsAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan error) {
+	artChan := make(chan Article, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(artChan)
+		defer close(errChan)
+		for _, doc := range d.docs {
+			select {
+			case <-stop:
+				return
+			case artChan <- doc:
+			}
+		}
+	}()
+
+	return artChan, errChan
+}
+
+type documentsArticle struct {
+	path    string
+	authors []string
+	date    time.Time
+}
+
+func (d *documentsArticle) ID() string {
+	abs, _ := filepath.Abs(d.path)
+	return hashID(abs)
+}
+
+func (d *documentsArticle) URL() string {
+	return d.path
+}
+
+func (d *documentsArticle) Body() (string, error) {
+	z, err := zip.OpenReader(d.path)
+	if err != nil {
+		return "", err
+	}
+	defer z.Close()
+	text, err := documentsFormat(d.path).text(&z.Reader)
+	if err != nil {
+		return "", err
+	} else if text == "" {
+		return "", errors.New("no body text found")
+	}
+	return text, nil
+}
+
+func (d *documentsArticle) Document() (*Document, error) {
+	return bodyDocument(d)
+}
+
+// Date returns the creation date from the metadata, or
+// the modification time of the file if there is none.
+func (d *documentsArticle) Date() (time.Time, error) {
+	return d.date, nil
+}
+
+func (d *documentsArticle) Authors() ([]string, error) {
+	return d.authors, nil
+}
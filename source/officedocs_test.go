@@ -0,0 +1,132 @@
+package source
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDocuments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "documents_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeZip(t, filepath.Join(dir, "book.epub"), map[string]string{
+		"mimetype": "application/epub+zip",
+		"META-INF/container.xml": `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`,
+		"OEBPS/content.opf": `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="2.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">
+    <dc:creator opf:role="aut">John Roe</dc:creator>
+    <dc:creator opf:role="ill">Ann Artist</dc:creator>
+    <dc:date>2016-11-14</dc:date>
+  </metadata>
+  <manifest>
+    <item id="ch2" href="text/chapter%202.xhtml" media-type="application/xhtml+xml"/>
+    <item id="ch1" href="text/ch1.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine><itemref idref="ch1"/><itemref idref="ch2"/></spine>
+</package>`,
+		"OEBPS/text/ch1.xhtml": `<html xmlns="http://www.w3.org/1999/xhtml"><body>
+<h1>Chapter One</h1><p>It was a <em>dark</em> night.</p></body></html>`,
+		"OEBPS/text/chapter 2.xhtml": `<html xmlns="http://www.w3.org/1999/xhtml"><body>
+<p>The end.</p></body></html>`,
+	})
+
+	os.Mkdir(filepath.Join(dir, "Jane Doe"), 0755)
+	writeZip(t, filepath.Join(dir, "Jane Doe", "essay.docx"), map[string]string{
+		"docProps/core.xml": `<?xml version="1.0"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties"
+  xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/">
+  <dc:creator>Janet Doe</dc:creator>
+  <dcterms:created>2016-11-20T10:00:00Z</dcterms:created>
+</cp:coreProperties>`,
+		"word/document.xml": `<?xml version="1.0"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:body>
+    <w:p><w:r><w:t>First </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>paragraph</w:t></w:r><w:r><w:t>.</w:t></w:r></w:p>
+    <w:p><w:pPr><w:pStyle w:val="Normal"/></w:pPr></w:p>
+    <w:p><w:r><w:t>Second</w:t><w:br/><w:t>line.</w:t></w:r></w:p>
+  </w:body>
+</w:document>`,
+	})
+	writeZip(t, filepath.Join(dir, "Jane Doe", "letter.odt"), map[string]string{
+		"content.xml": `<?xml version="1.0"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+  xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+  <office:body><office:text>
+    <text:h text:outline-level="1">Dear  reader,</text:h>
+    <text:p>A <text:span>styled</text:span> word.<text:note><text:note-body><text:p>A footnote.</text:p></text:note-body></text:note></text:p>
+  </office:text></office:body>
+</office:document-content>`,
+	})
+	writeZip(t, filepath.Join(dir, "joint.docx"), map[string]string{
+		"docProps/core.xml": `<cp:coreProperties
+  xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties"
+  xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:creator>Jane Doe; John Roe</dc:creator></cp:coreProperties>`,
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:body><w:p><w:r><w:t>Written together.</w:t></w:r></w:p></w:body></w:document>`,
+	})
+	ioutil.WriteFile(filepath.Join(dir, "Jane Doe", "notes.txt"), []byte("ignored"), 0644)
+
+	// A corrupt document should be skipped without ending
+	// the listing.
+	ioutil.WriteFile(filepath.Join(dir, "Jane Doe", "broken.docx"), []byte("not a zip"), 0644)
+
+	setEnv(t, map[string]string{"DOCUMENTS_DIR": dir})
+
+	bodies := map[string][]string{}
+	days := map[string][]int{}
+	for _, author := range collectAuthors(t, Documents{}) {
+		for _, art := range collectArticles(t, author) {
+			body, err := art.Body()
+			if err != nil {
+				t.Fatal(err)
+			}
+			date, _ := art.Date()
+			if names, _ := art.Authors(); (body == "Written together.") != (len(names) == 2) {
+				t.Errorf("unexpected authors for %q: %v", body, names)
+			}
+			bodies[author.Name()] = append(bodies[author.Name()], body)
+			days[author.Name()] = append(days[author.Name()], date.Day())
+		}
+	}
+	expected := map[string][]string{
+		"Jane Doe":  {"Dear reader,\n\nA styled word.", "Written together."},
+		"Janet Doe": {"First paragraph.\n\nSecond\nline."},
+		"John Roe":  {"Chapter One\n\nIt was a dark night.\n\nThe end.", "Written together."},
+	}
+	if !reflect.DeepEqual(bodies, expected) {
+		t.Errorf("unexpected bodies: %#v", bodies)
+	}
+	if days["Janet Doe"][0] != 20 || days["John Roe"][0] != 14 {
+		t.Errorf("unexpected days: %v", days)
+	}
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, contents := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(contents))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,284 @@
+package source
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yhat/scrape"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const quoraDefaultURL = "https://www.quora.com"
+
+// Quora is a Source that fetches answers from the Q&A
+// forum Quora (https://www.quora.com).
+//
+// Each user is an author, and each of their answers is an
+// article.
+type Quora struct {
+	// client makes the requests for the source, or is
+	// nil to use HTTPClient.
+	client *http.Client
+}
+
+// Help returns the usage information for this Source.
+func (_ Quora) Help() string {
+	return "Fetch answers from Quora.\n" +
+		"Environment variables:\n" +
+		" QUORA_USERS   comma-separated profile names, e.g. Jane-Doe (required)\n" +
+		" QUORA_URL     override the site root URL"
+}
+
+// withClient returns a copy of the source which makes its
+// requests with client.
+func (q Quora) withClient(client *http.Client) Source {
+	q.client = client
+	return q
+}
+
+// Authors produces an author for each user listed in the
+// QUORA_USERS environment variable.
+func (q Quora) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+	authChan := make(chan Author, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(authChan)
+		defer close(errChan)
+
+		root := os.Getenv("QUORA_URL")
+		if root == "" {
+			root = quoraDefaultURL
+		}
+		users := os.Getenv("QUORA_USERS")
+		if users == "" {
+			errChan <- errors.New("missing QUORA_USERS")
+			return
+		}
+
+		for _, user := range strings.Split(users, ",") {
+			user = strings.TrimSpace(user)
+			if user == "" {
+				continue
+			}
+			select {
+			case <-stop:
+				return
+			case authChan <- &quoraAuthor{root: root, user: user, client: q.client}:
+			}
+		}
+	}()
+
+	return authChan, errChan
+}
+
+type quoraAuthor struct {
+	root   string
+	user   string
+	client *http.Client
+}
+
+// Name derives a name from the profile name, which is
+// the user's name with dashes and a disambiguating
+// number.
+func (q *quoraAuthor) Name() string {
+	return strings.Replace(q.user, "-", " ", -1)
+}
+
+func (q *quoraAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan error) {
+	artChan := make(chan Article, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(artChan)
+		defer close(errChan)
+
+		pageURL := q.root + "/profile/" + url.PathEscape(q.user) + "/answers"
+		for pageURL != "" {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			var urls []string
+			var err error
+			urls, pageURL, err = q.fetchPage(pageURL)
+			if err != nil {
+				errChan <- err
+				return
+			}
+
+			for _, u := range urls {
+				select {
+				case <-stop:
+					return
+				case artChan <- &quoraAnswer{url: u, author: q.Name(), client: q.client}:
+				}
+			}
+		}
+	}()
+
+	return artChan, errChan
+}
+
+// fetchPage returns the answer permalinks on one page of
+// a user's answer list, along with the URL of the next
+// page (or "" if this is the last page).
+func (q *quoraAuthor) fetchPage(pageURL string) (urls []string, next string, err error) {
+	parsed, err := getHTML(q.client, pageURL)
+	if err != nil {
+		return
+	}
+
+	links := scrape.FindAll(parsed, scrape.ByClass("answer_permalink"))
+	for _, link := range links {
+		if href := scrape.Attr(link, "href"); href != "" {
+			urls = append(urls, resolveURL(pageURL, href))
+		}
+	}
+
+	nextLink, ok := scrape.Find(parsed, func(n *html.Node) bool {
+		return n.DataAtom == atom.A && scrape.Attr(n, "rel") == "next"
+	})
+	if ok {
+		next = resolveURL(pageURL, scrape.Attr(nextLink, "href"))
+	}
+
+	return
+}
+
+type quoraAnswer struct {
+	url    string
+	author string
+	client *http.Client
+
+	pageLock sync.Mutex
+	page     *html.Node
+}
+
+func (q *quoraAnswer) ID() string {
+	return hashID(q.url)
+}
+
+func (q *quoraAnswer) URL() string {
+	return q.url
+}
+
+// Body returns the text of the answer, excluding quotes
+// of the question and embedded content (images, videos,
+// link previews and code).
+func (q *quoraAnswer) Body() (string, error) {
+	doc, err := q.Document()
+	if err != nil {
+		return "", err
+	}
+	return doc.Text(), nil
+}
+
+func (q *quoraAnswer) Document() (*Document, error) {
+	answer, err := q.answerNode()
+	if err != nil {
+		return nil, err
+	}
+
+	content, ok := scrape.Find(answer, scrape.ByClass("rendered_qtext"))
+	if !ok {
+		return nil, errors.New("no answer text found")
+	}
+	// The page is cached and may be read by other calls, so
+	// embedded content is removed from a copy.
+	content = cloneNode(content)
+
+	question := ""
+	if qNode, ok := scrape.Find(q.page, scrape.ByClass("question_text")); ok {
+		question = quoraNormalize(nodeText(qNode))
+	}
+
+	removeNodes(content, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Blockquote, atom.Img, atom.Iframe, atom.Figure, atom.Pre,
+			atom.Code, atom.Video, atom.Script, atom.Style:
+			return true
+		}
+		for _, class := range []string{"qtext_embed", "qlink_container",
+			"qtext_image_wrapper", "qtext_code"} {
+			if scrape.ByClass(class)(n) {
+				return true
+			}
+		}
+		return false
+	})
+
+	doc := &Document{}
+	for _, p := range scrape.FindAll(content, func(n *html.Node) bool {
+		return n.DataAtom == atom.P || n.DataAtom == atom.Li
+	}) {
+		// Some answers open by restating the question.
+		if question != "" && quoraNormalize(nodeText(p)) == question {
+			continue
+		}
+		if p.DataAtom == atom.Li {
+			doc.addBlock(htmlInlineBlock(p, ListItem))
+		} else {
+			doc.addBlock(htmlInlineBlock(p, Prose))
+		}
+	}
+	if len(doc.Blocks) == 0 {
+		return nil, errors.New("empty answer")
+	}
+	return doc, nil
+}
+
+func (q *quoraAnswer) Authors() ([]string, error) {
+	return []string{q.author}, nil
+}
+
+// Date parses the "Written" or "Answered" date attached
+// to the answer's permalink.
+func (q *quoraAnswer) Date() (time.Time, error) {
+	answer, err := q.answerNode()
+	if err != nil {
+		return time.Time{}, err
+	}
+	link, ok := scrape.Find(answer, scrape.ByClass("answer_permalink"))
+	if !ok {
+		return time.Time{}, nil
+	}
+	match := quoraDateExpr.FindString(scrape.Text(link))
+	if match == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("Jan 2, 2006", match)
+}
+
+var quoraDateExpr = regexp.MustCompile(`[A-Z][a-z]{2} [0-9]{1,2}, [0-9]{4}`)
+
+func (q *quoraAnswer) answerNode() (*html.Node, error) {
+	q.pageLock.Lock()
+	defer q.pageLock.Unlock()
+	if q.page == nil {
+		page, err := getHTML(q.client, q.url)
+		if err != nil {
+			return nil, err
+		}
+		q.page = page
+	}
+	answer, ok := scrape.Find(q.page, scrape.ByClass("Answer"))
+	if !ok {
+		return nil, errors.New("no answer found")
+	}
+	return answer, nil
+}
+
+func quoraNormalize(s string) string {
+	return strings.ToLower(strings.TrimRight(strings.Join(strings.Fields(s), " "), "?"))
+}
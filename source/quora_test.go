@@ -0,0 +1,77 @@
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/yhat/scrape"
+
+	"golang.org/x/net/html/atom"
+)
+
+func TestQuora(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/profile/Jane-Doe/answers", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			serveFixture(w, "quora/answers2.html", "")
+		} else {
+			serveFixture(w, "quora/answers.html", "")
+		}
+	})
+	mux.HandleFunc("/Why-is-the-sky-blue/answer/Jane-Doe", func(w http.ResponseWriter, r *http.Request) {
+		serveFixture(w, "quora/sky.html", "")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	setEnv(t, map[string]string{
+		"QUORA_URL":   server.URL,
+		"QUORA_USERS": "Jane-Doe",
+	})
+
+	authors := collectAuthors(t, Quora{})
+	if len(authors) != 1 || authors[0].Name() != "Jane Doe" {
+		t.Fatal("unexpected authors:", authors)
+	}
+
+	arts := collectArticles(t, authors[0])
+	if len(arts) != 3 {
+		t.Fatal("expected 3 answers but got", len(arts))
+	}
+
+	body, err := arts[0].Body()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Honestly, I had to look this one up.\n\nShort wavelengths scatter more."
+	if body != expected {
+		t.Errorf("unexpected body: %q", body)
+	}
+
+	// The cached page must be left intact for later calls.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			arts[0].Date()
+			if body1, _ := arts[0].Body(); body1 != expected {
+				t.Errorf("unexpected body on later call: %q", body1)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, ok := scrape.Find(arts[0].(*quoraAnswer).page, scrape.ByTag(atom.Blockquote)); !ok {
+		t.Error("cached page was modified")
+	}
+
+	date, err := arts[0].Date()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if date.Year() != 2016 || date.Month() != 3 || date.Day() != 4 {
+		t.Errorf("unexpected date: %v", date)
+	}
+}
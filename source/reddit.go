@@ -0,0 +1,313 @@
+package source
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const redditNumBuckets = 64
+
+// Reddit is a Source that reads comments and submissions
+// from local Pushshift-style Reddit dumps.
+//
+// Dumps are newline-delimited JSON files, optionally
+// compressed with zstd (.zst) or gzip (.gz).
+// Since dumps are ordered by time rather than by author,
+// posts are first spilled into temporary files bucketed by
+// author, so that only one bucket needs to be in memory
+// at a time.
+type Reddit struct{}
+
+// Help returns the usage information for this Source.
+func (_ Reddit) Help() string {
+	return "Read comments and submissions from Reddit NDJSON dumps.\n" +
+		"Environment variables:\n" +
+		" REDDIT_FILES        comma-separated dump paths or globs (required)\n" +
+		" REDDIT_MIN_LENGTH   minimum post length in bytes (default 100)\n" +
+		" REDDIT_MIN_COUNT    minimum posts per author (default 2)\n" +
+		" REDDIT_BOTS         regexp of bot names to skip (default " + redditDefaultBots + ")\n" +
+		" REDDIT_TMP          directory for temporary files"
+}
+
+// Authors reads all the dumps and then produces every
+// author with enough posts.
+func (_ Reddit) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+	authChan := make(chan Author, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(authChan)
+		defer close(errChan)
+
+		files, err := globList("REDDIT_FILES")
+		if err != nil {
+			errChan <- err
+			return
+		}
+		minLength, err := envInt("REDDIT_MIN_LENGTH", 100)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		minCount, err := envInt("REDDIT_MIN_COUNT", 2)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		botExpr := os.Getenv("REDDIT_BOTS")
+		if botExpr == "" {
+			botExpr = redditDefaultBots
+		}
+		bots, err := regexp.Compile(botExpr)
+		if err != nil {
+			errChan <- errors.New("invalid REDDIT_BOTS: " + err.Error())
+			return
+		}
+
+		tempDir, err := ioutil.TempDir(os.Getenv("REDDIT_TMP"), "reddit")
+		if err != nil {
+			errChan <- err
+			return
+		}
+		defer os.RemoveAll(tempDir)
+
+		if err := redditSpill(files, tempDir, minLength, bots); err != nil {
+			errChan <- err
+			return
+		}
+
+		for i := 0; i < redditNumBuckets; i++ {
+			authors, err := redditReadBucket(redditBucketPath(tempDir, i), minCount)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			for _, a := range authors {
+				select {
+				case <-stop:
+					return
+				case authChan <- a:
+				}
+			}
+		}
+	}()
+
+	return authChan, errChan
+}
+
+// redditRecord is a comment or submission in a dump.
+type redditRecord struct {
+	ID         string      `json:"id"`
+	Author     string      `json:"author"`
+	Body       string      `json:"body"`
+	Selftext   string      `json:"selftext"`
+	CreatedUTC json.Number `json:"created_utc"`
+}
+
+// redditPost is a filtered post stored in a bucket.
+type redditPost struct {
+	Author  string
+	PostID  string
+	Text    string
+	Created int64
+}
+
+// redditDefaultBots matches AutoModerator and names which
+// end in "Bot" or in "bot" after a dash or underscore,
+// optionally followed by a number, so that users like
+// "talbot" are kept.
+const redditDefaultBots = `^((?i:automoderator)|.*(Bot|[-_](?i:bot))[-_]?[0-9]*)$`
+
+func redditSpill(files []string, tempDir string, minLength int, bots *regexp.Regexp) error {
+	var buffers [redditNumBuckets]*bufio.Writer
+	var encoders [redditNumBuckets]*json.Encoder
+	for i := range buffers {
+		f, err := os.Create(redditBucketPath(tempDir, i))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		buffers[i] = bufio.NewWriter(f)
+		encoders[i] = json.NewEncoder(buffers[i])
+	}
+
+	for _, path := range files {
+		err := redditScanFile(path, func(r *redditRecord) error {
+			body := r.Body
+			if body == "" {
+				body = r.Selftext
+			}
+			body = strings.TrimSpace(body)
+			if len(body) < minLength || body == "[deleted]" || body == "[removed]" ||
+				r.Author == "" || r.Author == "[deleted]" ||
+				bots.MatchString(r.Author) {
+				return nil
+			}
+			created, _ := strconv.ParseFloat(r.CreatedUTC.String(), 64)
+			hash := fnv.New32a()
+			hash.Write([]byte(r.Author))
+			return encoders[hash.Sum32()%redditNumBuckets].Encode(&redditPost{
+				Author:  r.Author,
+				PostID:  r.ID,
+				Text:    body,
+				Created: int64(created),
+			})
+		})
+		if err != nil {
+			return errors.New(path + ": " + err.Error())
+		}
+	}
+
+	for _, b := range buffers {
+		if err := b.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func redditScanFile(path string, f func(r *redditRecord) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	switch filepath.Ext(path) {
+	case ".zst":
+		// Pushshift dumps are compressed with a large window.
+		dec, err := zstd.NewReader(file, zstd.WithDecoderMaxWindow(1<<31))
+		if err != nil {
+			return err
+		}
+		defer dec.Close()
+		reader = dec
+	case ".gz":
+		dec, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer dec.Close()
+		reader = dec
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var record redditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return err
+		}
+		if err := f(&record); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func redditBucketPath(tempDir string, idx int) string {
+	return filepath.Join(tempDir, strconv.Itoa(idx)+".json")
+}
+
+func redditReadBucket(path string, minCount int) ([]*redditAuthor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	posts := map[string][]*redditPost{}
+	dec := json.NewDecoder(f)
+	for {
+		var post redditPost
+		if err := dec.Decode(&post); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		posts[post.Author] = append(posts[post.Author], &post)
+	}
+
+	var res []*redditAuthor
+	for name, list := range posts {
+		if len(list) >= minCount {
+			res = append(res, &redditAuthor{name: name, posts: list})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].name < res[j].name
+	})
+	return res, nil
+}
+
+type redditAuthor struct {
+	name  string
+	posts []*redditPost
+}
+
+func (r *redditAuthor) Name() string {
+	return r.name
+}
+
+func (r *redditAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan error) {
+	artChan := make(chan Article, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(artChan)
+		defer close(errChan)
+		for _, p := range r.posts {
+			select {
+			case <-stop:
+				return
+			case artChan <- (*redditArticle)(p):
+			}
+		}
+	}()
+
+	return artChan, errChan
+}
+
+type redditArticle redditPost
+
+func (r *redditArticle) ID() string {
+	return hashID("reddit:" + r.PostID)
+}
+
+func (r *redditArticle) Body() (string, error) {
+	return r.Text, nil
+}
+
+func (r *redditArticle) Document() (*Document, error) {
+	return bodyDocument(r)
+}
+
+func (r *redditArticle) Authors() ([]string, error) {
+	return []string{r.Author}, nil
+}
+
+func (r *redditArticle) Date() (time.Time, error) {
+	if r.Created == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(r.Created, 0).UTC(), nil
+}
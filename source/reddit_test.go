@@ -0,0 +1,108 @@
+package source
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const redditTestComments = `{"id":"c1","author":"jane_doe","body":"The first comment by Jane, which is long enough to keep.","created_utc":1480000000}
+{"id":"c2","author":"[deleted]","body":"A deleted author's comment that is long enough to keep.","created_utc":1480000001}
+{"id":"c3","author":"RemindMeBot","body":"I will be messaging you in 2 days to remind you of this.","created_utc":1480000002}
+{"id":"c4","author":"john_roe","body":"Too short.","created_utc":"1480000003"}
+{"id":"c5","author":"john_roe","body":"[removed]","created_utc":1480000004}
+
+{"id":"c6","author":"john_roe","body":"The only long comment by John, so John lacks enough posts.","created_utc":1480000005}
+`
+
+const redditTestSubmissions = `{"id":"s1","author":"jane_doe","title":"A post","selftext":"A self post by Jane, long enough to be kept as an article.","created_utc":"1480000100"}
+{"id":"s2","author":"jane_doe","title":"A link","selftext":"","url":"http://example.com","created_utc":1480000200}
+`
+
+func TestReddit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reddit_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeCompressed(t, filepath.Join(dir, "RC_2016-11.gz"), redditTestComments,
+		func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		})
+	writeCompressed(t, filepath.Join(dir, "RS_2016-11.zst"), redditTestSubmissions,
+		func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w)
+		})
+
+	setEnv(t, map[string]string{
+		"REDDIT_FILES":      filepath.Join(dir, "RC_*") + "," + filepath.Join(dir, "RS_*"),
+		"REDDIT_MIN_LENGTH": "30",
+		"REDDIT_MIN_COUNT":  "2",
+		"REDDIT_TMP":        dir,
+	})
+
+	authors := collectAuthors(t, Reddit{})
+	if len(authors) != 1 || authors[0].Name() != "jane_doe" {
+		t.Fatal("unexpected authors:", authors)
+	}
+	arts := collectArticles(t, authors[0])
+	if len(arts) != 2 {
+		t.Fatal("expected 2 articles but got", len(arts))
+	}
+	body, _ := arts[1].Body()
+	if !strings.HasPrefix(body, "A self post by Jane") {
+		t.Errorf("unexpected body: %q", body)
+	}
+	date, _ := arts[1].Date()
+	if date.Unix() != 1480000100 {
+		t.Errorf("unexpected date: %v", date)
+	}
+
+	setEnv(t, map[string]string{"REDDIT_MIN_COUNT": "1"})
+	authors = collectAuthors(t, Reddit{})
+	if len(authors) != 2 {
+		t.Fatal("expected 2 authors but got", len(authors))
+	}
+}
+
+func writeCompressed(t *testing.T, path, data string,
+	f func(w io.Writer) (io.WriteCloser, error)) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	w, err := f(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedditDefaultBots(t *testing.T) {
+	bots := regexp.MustCompile(redditDefaultBots)
+	for _, name := range []string{"AutoModerator", "RemindMeBot", "image-bot", "stats_BOT",
+		"GoodBot_2", "tweet_bot99"} {
+		if !bots.MatchString(name) {
+			t.Errorf("expected %q to be a bot", name)
+		}
+	}
+	for _, name := range []string{"talbot", "Abbot", "botanist", "robot_lover", "Jane"} {
+		if bots.MatchString(name) {
+			t.Errorf("expected %q not to be a bot", name)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package source
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 // A Source represents a collection of textual articles
 // written by various authors.
@@ -34,11 +37,99 @@ type Article interface {
 	// Body attempts to retrieve the body of the article.
 	Body() (string, error)
 
+	// Document attempts to retrieve the body of the article
+	// with its prose, quotes, headings, captions and lists
+	// kept apart.
+	// Rendering it with Text gives the same result as Body.
+	Document() (*Document, error)
+
 	// Date attempts to retrieve the date of the publication.
 	// If no date is available, it returns the zero time.
 	// It only returns an error if it cannot fetch the date
 	// even though there may be one (e.g. due to HTTP error).
 	Date() (time.Time, error)
+
+	// Authors attempts to retrieve the names of everybody
+	// credited with the article, including the Author it
+	// was listed under.
+	// Co-authored articles have more than one name.
+	Authors() ([]string, error)
+}
+
+// A PagedAuthor is an Author whose articles are listed
+// on numbered pages, so that a crawl can resume from the
+// page where it stopped.
+type PagedAuthor interface {
+	Author
+
+	// ArticlesFrom is like Articles, but it starts listing
+	// at the given page, where the first page is 1.
+	ArticlesFrom(page int, stop <-chan struct{}) (<-chan Article, <-chan error)
+}
+
+// A PagedArticle is an Article from a PagedAuthor.
+type PagedArticle interface {
+	Article
+
+	// ListingPage returns the page the article was listed
+	// on.
+	ListingPage() int
+}
+
+// A KeyedAuthor is an Author which its Source can restore
+// from a key, without listing every author again.
+type KeyedAuthor interface {
+	Author
+
+	// Key returns a string which identifies the author to
+	// the source's RestoreAuthor.
+	Key() string
+}
+
+// A RestorableSource is a Source whose KeyedAuthors can be
+// restored, so that a resumed crawl need not list every
+// author again.
+type RestorableSource interface {
+	Source
+
+	// RestoreAuthor recreates an author from the name and
+	// key of a KeyedAuthor listed by an earlier run.
+	RestoreAuthor(name, key string) (Author, error)
+}
+
+// A LocatedArticle is an Article which can report where
+// it came from.
+type LocatedArticle interface {
+	Article
+
+	// URL returns the address of the article's page, or the
+	// path of a local file.
+	URL() string
 }
 
-var Sources = map[string]Source{"NewYorker": NewYorker{}}
+// ErrSubscriberOnly is returned when an article's full
+// text is only available to logged-in subscribers.
+var ErrSubscriberOnly = errors.New("article is subscriber-only")
+
+// ErrExcluded is returned when a source's own filters
+// exclude an article which could only be checked once the
+// article was fetched.
+var ErrExcluded = errors.New("article is excluded by the source's filters")
+
+var Sources = map[string]Source{
+	"NewYorker":     NewYorker{},
+	"Chat":          Chat{},
+	"Documents":     Documents{},
+	"Email":         Email{},
+	"Feed":          Feed{},
+	"Git":           Git{},
+	"Gutenberg":     Gutenberg{},
+	"Manifest":      Manifest{},
+	"NYTimes":       NYTimes{},
+	"News":          News{},
+	"Quora":         Quora{},
+	"Reddit":        Reddit{},
+	"StackExchange": StackExchange{},
+	"StaticSite":    StaticSite{},
+	"WARC":          WARC{},
+}
@@ -0,0 +1,62 @@
+package source
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serveFixture writes a file from testdata, substituting
+// the test server's URL for {{server}}.
+func serveFixture(w http.ResponseWriter, name, serverURL string) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		http.NotFound(w, nil)
+		return
+	}
+	w.Write([]byte(strings.Replace(string(data), "{{server}}", serverURL, -1)))
+}
+
+// setEnv sets environment variables for the duration of
+// a test.
+func setEnv(t *testing.T, vars map[string]string) {
+	for k, v := range vars {
+		old, had := os.LookupEnv(k)
+		os.Setenv(k, v)
+		k := k
+		t.Cleanup(func() {
+			if had {
+				os.Setenv(k, old)
+			} else {
+				os.Unsetenv(k)
+			}
+		})
+	}
+}
+
+func collectAuthors(t *testing.T, s Source) []Author {
+	authChan, errChan := s.Authors(nil)
+	var res []Author
+	for a := range authChan {
+		res = append(res, a)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func collectArticles(t *testing.T, a Author) []Article {
+	artChan, errChan := a.Articles(nil)
+	var res []Article
+	for art := range artChan {
+		res = append(res, art)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
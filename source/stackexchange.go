@@ -0,0 +1,275 @@
+package source
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	sePostTypeQuestion = "1"
+	sePostTypeAnswer   = "2"
+)
+
+// StackExchange is a Source that reads posts from an
+// offline Stack Exchange data dump.
+//
+// The dump's Posts.xml is scanned once to record where
+// each user's posts live, and individual posts are then
+// read back on demand, so the dump is never loaded into
+// memory at once.
+type StackExchange struct{}
+
+// Help returns the usage information for this Source.
+func (_ StackExchange) Help() string {
+	return "Read posts from an extracted Stack Exchange data dump.\n" +
+		"Environment variables:\n" +
+		" STACKEXCHANGE_DIR     directory with Posts.xml and Users.xml (required)\n" +
+		" STACKEXCHANGE_TYPES   post types to include: questions, answers,\n" +
+		"                       or both comma-separated (default both)"
+}
+
+// Authors produces the users from Users.xml who own at
+// least one post of an included type.
+func (_ StackExchange) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+	authChan := make(chan Author, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(authChan)
+		defer close(errChan)
+
+		dir := os.Getenv("STACKEXCHANGE_DIR")
+		if dir == "" {
+			errChan <- errors.New("missing STACKEXCHANGE_DIR")
+			return
+		}
+		types, err := seIncludedTypes()
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		postsPath := filepath.Join(dir, "Posts.xml")
+		index, err := seIndexPosts(postsPath, types)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		err = seScanRows(filepath.Join(dir, "Users.xml"), func(row *seRow, _ int64) bool {
+			posts := index[row.ID]
+			if len(posts) == 0 {
+				return true
+			}
+			author := &seAuthor{
+				name:      row.DisplayName + " (" + row.ID + ")",
+				postsPath: postsPath,
+				posts:     posts,
+			}
+			select {
+			case <-stop:
+				return false
+			case authChan <- author:
+			}
+			return true
+		})
+		if err != nil {
+			errChan <- err
+		}
+	}()
+
+	return authChan, errChan
+}
+
+func seIncludedTypes() (map[string]bool, error) {
+	env := os.Getenv("STACKEXCHANGE_TYPES")
+	if env == "" {
+		return map[string]bool{sePostTypeQuestion: true, sePostTypeAnswer: true}, nil
+	}
+	res := map[string]bool{}
+	for _, t := range strings.Split(env, ",") {
+		switch strings.TrimSpace(t) {
+		case "questions":
+			res[sePostTypeQuestion] = true
+		case "answers":
+			res[sePostTypeAnswer] = true
+		default:
+			return nil, errors.New("invalid STACKEXCHANGE_TYPES entry: " + t)
+		}
+	}
+	return res, nil
+}
+
+// seIndexPosts maps each user ID to the locations of
+// their posts in Posts.xml.
+func seIndexPosts(path string, types map[string]bool) (map[string][]sePostRef, error) {
+	res := map[string][]sePostRef{}
+	err := seScanRows(path, func(row *seRow, offset int64) bool {
+		if row.OwnerUserID != "" && types[row.PostTypeID] {
+			ref := sePostRef{id: row.ID, offset: offset}
+			res[row.OwnerUserID] = append(res[row.OwnerUserID], ref)
+		}
+		return true
+	})
+	return res, err
+}
+
+type sePostRef struct {
+	id     string
+	offset int64
+}
+
+// seRow stores the attributes of a <row> element from
+// either Posts.xml or Users.xml.
+type seRow struct {
+	ID           string `xml:"Id,attr"`
+	DisplayName  string `xml:"DisplayName,attr"`
+	PostTypeID   string `xml:"PostTypeId,attr"`
+	OwnerUserID  string `xml:"OwnerUserId,attr"`
+	CreationDate string `xml:"CreationDate,attr"`
+	Body         string `xml:"Body,attr"`
+}
+
+// seScanRows streams the <row> elements of a dump file,
+// passing each one to f along with its byte offset until
+// f returns false.
+func seScanRows(path string, f func(row *seRow, offset int64) bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return seScanReader(file, 0, f)
+}
+
+func seScanReader(r io.Reader, base int64, f func(row *seRow, offset int64) bool) error {
+	dec := xml.NewDecoder(r)
+	for {
+		offset := base + dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		var row seRow
+		if err := dec.DecodeElement(&row, &start); err != nil {
+			return err
+		}
+		if !f(&row, offset) {
+			return nil
+		}
+	}
+}
+
+// seReadRow reads the <row> at the given offset.
+func seReadRow(path string, offset int64) (*seRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var res *seRow
+	err = seScanReader(file, offset, func(row *seRow, _ int64) bool {
+		res = row
+		return false
+	})
+	if err != nil {
+		return nil, err
+	} else if res == nil {
+		return nil, errors.New("no post at offset " + strconv.FormatInt(offset, 10))
+	}
+	return res, nil
+}
+
+type seAuthor struct {
+	name      string
+	postsPath string
+	posts     []sePostRef
+}
+
+func (s *seAuthor) Name() string {
+	return s.name
+}
+
+func (s *seAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan error) {
+	artChan := make(chan Article, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(artChan)
+		defer close(errChan)
+		for _, ref := range s.posts {
+			select {
+			case <-stop:
+				return
+			case artChan <- &sePost{path: s.postsPath, ref: ref, author: s.name}:
+			}
+		}
+	}()
+
+	return artChan, errChan
+}
+
+type sePost struct {
+	path   string
+	ref    sePostRef
+	author string
+}
+
+func (s *sePost) ID() string {
+	return hashID("stackexchange:" + filepath.Base(filepath.Dir(s.path)) + ":" + s.ref.id)
+}
+
+// Body converts the HTML body of the post to plain text,
+// dropping code blocks.
+func (s *sePost) Body() (string, error) {
+	doc, err := s.Document()
+	if err != nil {
+		return "", err
+	}
+	return doc.Text(), nil
+}
+
+func (s *sePost) Document() (*Document, error) {
+	row, err := seReadRow(s.path, s.ref.offset)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := html.Parse(strings.NewReader(row.Body))
+	if err != nil {
+		return nil, err
+	}
+	return htmlDocument(parsed), nil
+}
+
+func (s *sePost) Authors() ([]string, error) {
+	return []string{s.author}, nil
+}
+
+func (s *sePost) Date() (time.Time, error) {
+	row, err := seReadRow(s.path, s.ref.offset)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if row.CreationDate == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02T15:04:05", row.CreationDate)
+}
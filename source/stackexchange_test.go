@@ -0,0 +1,54 @@
+package source
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStackExchange(t *testing.T) {
+	setEnv(t, map[string]string{
+		"STACKEXCHANGE_DIR":   filepath.Join("testdata", "stackexchange"),
+		"STACKEXCHANGE_TYPES": "",
+	})
+
+	authors := collectAuthors(t, StackExchange{})
+	if len(authors) != 2 || authors[0].Name() != "Jane Doe (5)" ||
+		authors[1].Name() != "John Roe (7)" {
+		t.Fatal("unexpected authors:", authors)
+	}
+
+	arts := collectArticles(t, authors[0])
+	if len(arts) != 2 {
+		t.Fatal("expected 2 posts but got", len(arts))
+	}
+	body, err := arts[0].Body()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "Use a loop that swaps the ends:\n\nIt runs in linear time." {
+		t.Errorf("unexpected body: %q", body)
+	}
+	body, err = arts[1].Body()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "Or call the reverse helper." {
+		t.Errorf("unexpected body: %q", body)
+	}
+	date, err := arts[1].Date()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if date.Year() != 2010 || date.Day() != 20 || date.Hour() != 8 {
+		t.Errorf("unexpected date: %v", date)
+	}
+	if arts[0].ID() == arts[1].ID() {
+		t.Error("duplicate IDs")
+	}
+
+	setEnv(t, map[string]string{"STACKEXCHANGE_TYPES": "questions"})
+	authors = collectAuthors(t, StackExchange{})
+	if len(authors) != 1 || authors[0].Name() != "John Roe (7)" {
+		t.Fatal("unexpected question authors:", authors)
+	}
+}
@@ -0,0 +1,335 @@
+package source
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StaticSite is a Source that reads Markdown posts from a
+// Hugo or Jekyll checkout, using the author named in each
+// post's YAML or TOML front matter.
+type StaticSite struct{}
+
+// Help returns the usage information for this Source.
+func (_ StaticSite) Help() string {
+	return "Read Markdown posts from a Hugo or Jekyll site.\n" +
+		"Environment variables:\n" +
+		" STATICSITE_DIR      root of the site checkout (required)\n" +
+		" STATICSITE_AUTHOR   author for posts without one in their front matter\n" +
+		"Only files with front matter are read, and drafts are skipped.\n" +
+		"Dates come from the front matter, then from a Jekyll-style\n" +
+		"YYYY-MM-DD- file name prefix, then from the file's modification\n" +
+		"time."
+}
+
+// Authors reads the front matter of every post and then
+// produces the authors in alphabetical order.
+func (_ StaticSite) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+	authChan := make(chan Author, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(authChan)
+		defer close(errChan)
+
+		dir := os.Getenv("STATICSITE_DIR")
+		if dir == "" {
+			errChan <- errors.New("missing STATICSITE_DIR")
+			return
+		}
+		defaultAuthor := os.Getenv("STATICSITE_AUTHOR")
+
+		authors := map[string]*staticSiteAuthor{}
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path != dir && staticSiteSkipDir(info.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".md", ".markdown", ".mdown":
+			default:
+				return nil
+			}
+
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			fields, _, ok := frontMatter(string(data))
+			if !ok || staticSiteIsDraft(fields) {
+				return nil
+			}
+			names := staticSiteAuthorNames(fields)
+			if len(names) == 0 && defaultAuthor != "" {
+				names = []string{defaultAuthor}
+			}
+			post := &staticSitePost{path: path, authors: names,
+				date: staticSiteDate(fields, info)}
+			for _, name := range names {
+				if authors[name] == nil {
+					authors[name] = &staticSiteAuthor{name: name}
+				}
+				authors[name].posts = append(authors[name].posts, post)
+			}
+			return nil
+		})
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		var names []string
+		for name := range authors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			select {
+			case <-stop:
+				return
+			case authChan <- authors[name]:
+			}
+		}
+	}()
+
+	return authChan, errChan
+}
+
+// staticSiteSkipDir returns true for hidden directories,
+// build output, dependencies and Jekyll drafts.
+func staticSiteSkipDir(name string) bool {
+	switch name {
+	case "_site", "_drafts", "public", "resources", "node_modules", "vendor":
+		return true
+	}
+	return strings.HasPrefix(name, ".")
+}
+
+func staticSiteIsDraft(fields map[string][]string) bool {
+	return frontMatterValue(fields, "draft") == "true" ||
+		frontMatterValue(fields, "published") == "false"
+}
+
+// staticSiteAuthorNames finds the authors in the various
+// places that themes expect them.
+func staticSiteAuthorNames(fields map[string][]string) []string {
+	for _, key := range []string{"author", "authors", "author.name", "params.author",
+		"params.authors"} {
+		var res []string
+		for _, name := range fields[key] {
+			if name = normalizeByline(name); name != "" {
+				res = append(res, name)
+			}
+		}
+		if len(res) > 0 {
+			return uniqueStrings(res)
+		}
+	}
+	return nil
+}
+
+var staticSiteFileDate = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-`)
+
+func staticSiteDate(fields map[string][]string, info os.FileInfo) time.Time {
+	if date := parseDate(frontMatterValue(fields, "date")); !date.IsZero() {
+		return date
+	}
+	if match := staticSiteFileDate.FindStringSubmatch(info.Name()); match != nil {
+		if date := parseDate(match[1]); !date.IsZero() {
+			return date
+		}
+	}
+	return info.ModTime()
+}
+
+var (
+	frontMatterYAMLKey = regexp.MustCompile(`^([\w-]+):\s*(.*)$`)
+	frontMatterTOMLKey = regexp.MustCompile(`^([\w-]+)\s*=\s*(.*)$`)
+	frontMatterTable   = regexp.MustCompile(`^\[([\w.-]+)\]$`)
+)
+
+// frontMatter splits a document into its front matter and
+// body.
+//
+// Front matter may be YAML (between "---" lines) or TOML
+// (between "+++" lines).
+// Only the simple subset used for post metadata is
+// understood: scalars, lists, and one level of nesting,
+// with nested keys joined by dots (e.g. "params.author").
+func frontMatter(doc string) (fields map[string][]string, body string, ok bool) {
+	doc = strings.TrimPrefix(doc, "\ufeff")
+	lines := strings.Split(strings.Replace(doc, "\r\n", "\n", -1), "\n")
+	if len(lines) == 0 {
+		return nil, doc, false
+	}
+	delim := strings.TrimSpace(lines[0])
+	if delim != "---" && delim != "+++" {
+		return nil, doc, false
+	}
+
+	fields = map[string][]string{}
+	var parent string
+	for i, line := range lines[1:] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == delim || (delim == "---" && trimmed == "...") {
+			return fields, strings.Join(lines[i+2:], "\n"), true
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if delim == "+++" {
+			if match := frontMatterTable.FindStringSubmatch(trimmed); match != nil {
+				parent = match[1]
+			} else if match := frontMatterTOMLKey.FindStringSubmatch(trimmed); match != nil {
+				key := match[1]
+				if parent != "" {
+					key = parent + "." + key
+				}
+				fields[key] = frontMatterValues(match[2])
+			}
+			continue
+		}
+
+		indented := line != strings.TrimLeft(line, " \t")
+		if !indented {
+			parent = ""
+			if match := frontMatterYAMLKey.FindStringSubmatch(trimmed); match != nil {
+				if match[2] == "" {
+					parent = match[1]
+				} else {
+					fields[match[1]] = frontMatterValues(match[2])
+				}
+			}
+		} else if parent != "" {
+			if strings.HasPrefix(trimmed, "- ") {
+				item := strings.TrimSpace(trimmed[2:])
+				if match := frontMatterYAMLKey.FindStringSubmatch(item); match != nil {
+					// A list of objects, such as "- name: Jane".
+					fields[parent+"."+match[1]] = append(fields[parent+"."+match[1]],
+						frontMatterValues(match[2])...)
+				} else {
+					fields[parent] = append(fields[parent], frontMatterValues(item)...)
+				}
+			} else if match := frontMatterYAMLKey.FindStringSubmatch(trimmed); match != nil {
+				fields[parent+"."+match[1]] = frontMatterValues(match[2])
+			}
+		}
+	}
+	return nil, doc, false
+}
+
+// frontMatterValues parses a scalar or an inline list.
+func frontMatterValues(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if !strings.HasPrefix(raw, "[") || !strings.HasSuffix(raw, "]") {
+		return []string{frontMatterUnquote(raw)}
+	}
+	var res []string
+	for _, item := range strings.Split(raw[1:len(raw)-1], ",") {
+		if item = frontMatterUnquote(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func frontMatterUnquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
+
+func frontMatterValue(fields map[string][]string, key string) string {
+	if values := fields[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+type staticSiteAuthor struct {
+	name  string
+	posts []*staticSitePost
+}
+
+func (s *staticSiteAuthor) Name() string {
+	return s.name
+}
+
+func (s *staticSiteAuthor) Articles(stop <-chan struct{}) (<-chan Article, <-chan error) {
+	artChan := make(chan Article, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(artChan)
+		defer close(errChan)
+		for _, p := range s.posts {
+			select {
+			case <-stop:
+				return
+			case artChan <- p:
+			}
+		}
+	}()
+
+	return artChan, errChan
+}
+
+type staticSitePost struct {
+	path    string
+	authors []string
+	date    time.Time
+}
+
+func (s *staticSitePost) ID() string {
+	abs, _ := filepath.Abs(s.path)
+	return hashID(abs)
+}
+
+func (s *staticSitePost) URL() string {
+	return s.path
+}
+
+func (s *staticSitePost) Body() (string, error) {
+	doc, err := s.Document()
+	if err != nil {
+		return "", err
+	}
+	return doc.Text(), nil
+}
+
+func (s *staticSitePost) Document() (*Document, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	_, body, _ := frontMatter(string(data))
+	if doc := markdownDocument(body); len(doc.Blocks) > 0 {
+		return doc, nil
+	}
+	return nil, errors.New("no body text found")
+}
+
+func (s *staticSitePost) Date() (time.Time, error) {
+	return s.date, nil
+}
+
+func (s *staticSitePost) Authors() ([]string, error) {
+	return s.authors, nil
+}
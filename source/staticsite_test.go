@@ -0,0 +1,58 @@
+package source
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStaticSite(t *testing.T) {
+	setEnv(t, map[string]string{
+		"STATICSITE_DIR":    filepath.Join("testdata", "staticsite"),
+		"STATICSITE_AUTHOR": "",
+	})
+	bodies, days := staticSiteCollect(t)
+	expected := map[string][]string{
+		"Jane Doe": {
+			"Written together.",
+			"Hello\n\nThis is the first post, with a link and a hard-wrapped line.\n\n" +
+				"One item\n\nAnother item\n\nA quoted line.",
+			"Written with in mind.",
+		},
+		"John Roe": {
+			"Jekyll posts take their date from the file name.",
+			"A nested author.",
+			"Written together.",
+		},
+	}
+	if !reflect.DeepEqual(bodies, expected) {
+		t.Errorf("unexpected bodies: %#v", bodies)
+	}
+	expectedDays := map[string][]int{"Jane Doe": {18, 14, 16}, "John Roe": {15, 17, 18}}
+	if !reflect.DeepEqual(days, expectedDays) {
+		t.Errorf("unexpected days: %v", days)
+	}
+
+	setEnv(t, map[string]string{"STATICSITE_AUTHOR": "Site Owner"})
+	bodies, _ = staticSiteCollect(t)
+	if len(bodies) != 3 || len(bodies["Site Owner"]) != 1 {
+		t.Errorf("unexpected bodies with default author: %#v", bodies)
+	}
+}
+
+func staticSiteCollect(t *testing.T) (map[string][]string, map[string][]int) {
+	bodies := map[string][]string{}
+	days := map[string][]int{}
+	for _, author := range collectAuthors(t, StaticSite{}) {
+		for _, art := range collectArticles(t, author) {
+			body, err := art.Body()
+			if err != nil {
+				t.Fatal(err)
+			}
+			date, _ := art.Date()
+			bodies[author.Name()] = append(bodies[author.Name()], body)
+			days[author.Name()] = append(days[author.Name()], date.Day())
+		}
+	}
+	return bodies, days
+}
@@ -0,0 +1,324 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// WARC is a Source that replays another HTML-based Source
+// against responses archived in WARC files, rather than
+// fetching them live.
+//
+// This makes it possible to crawl a site once (e.g. with
+// wget --warc-file) and rebuild datasets deterministically
+// as the extraction logic improves.
+type WARC struct{}
+
+// Help returns the usage information for this Source.
+func (_ WARC) Help() string {
+	return "Replay another source against archived WARC files.\n" +
+		"Environment variables:\n" +
+		" WARC_FILES    comma-separated .warc or .warc.gz paths or globs (required)\n" +
+		" WARC_SOURCE   name of the source to replay (default NewYorker);\n" +
+		"               one of NewYorker, NYTimes, Quora, News, Feed or Manifest\n" +
+		"The replayed source's own environment variables also apply.\n" +
+		"Pages missing from the archive produce 404 errors."
+}
+
+// Authors indexes the archives and then lists authors
+// from the replayed source, which makes its requests with
+// a client of its own rather than HTTPClient.
+func (_ WARC) Authors(stop <-chan struct{}) (<-chan Author, <-chan error) {
+	name := os.Getenv("WARC_SOURCE")
+	if name == "" {
+		name = "NewYorker"
+	}
+	s, ok := Sources[name].(replayableSource)
+	if !ok {
+		return failedAuthors(errors.New("invalid WARC_SOURCE: " + name))
+	}
+
+	paths, err := globList("WARC_FILES")
+	if err != nil {
+		return failedAuthors(err)
+	}
+	transport, err := NewWARCTransport(paths)
+	if err != nil {
+		return failedAuthors(err)
+	}
+	return s.withClient(&http.Client{Transport: transport}).Authors(stop)
+}
+
+// A replayableSource is a Source which can make its
+// requests with a given client.
+type replayableSource interface {
+	Source
+	withClient(client *http.Client) Source
+}
+
+// failedAuthors produces the result of an Authors call
+// that failed before listing anything.
+func failedAuthors(err error) (<-chan Author, <-chan error) {
+	authChan := make(chan Author)
+	errChan := make(chan error, 1)
+	close(authChan)
+	errChan <- err
+	close(errChan)
+	return authChan, errChan
+}
+
+// A WARCTransport is an http.RoundTripper which serves
+// responses from WARC files.
+//
+// URLs are matched regardless of their scheme, so that a
+// crawl recorded over HTTPS can serve HTTP requests.
+// Requests for unarchived URLs get a 404 response.
+type WARCTransport struct {
+	index map[string]warcLocation
+}
+
+type warcLocation struct {
+	path   string
+	offset int64
+}
+
+// NewWARCTransport indexes the response records in the
+// given WARC files, which may be gzipped.
+// When a URL was archived more than once, the last
+// response is used.
+func NewWARCTransport(paths []string) (*WARCTransport, error) {
+	res := &WARCTransport{index: map[string]warcLocation{}}
+	for _, path := range paths {
+		err := warcScan(path, func(header textproto.MIMEHeader, offset int64) {
+			if header.Get("Warc-Type") != "response" {
+				return
+			}
+			key := warcKey(header.Get("Warc-Target-Uri"))
+			res.index[key] = warcLocation{path: path, offset: offset}
+		})
+		if err != nil {
+			return nil, errors.New(path + ": " + err.Error())
+		}
+	}
+	return res, nil
+}
+
+// RoundTrip serves an archived response.
+func (w *WARCTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	loc, ok := w.index[warcKey(req.URL.String())]
+	if !ok {
+		return &http.Response{
+			Status:     "404 Not Found",
+			StatusCode: http.StatusNotFound,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+			Request:    req,
+		}, nil
+	}
+
+	block, err := warcReadBlock(loc)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Archived responses are stored as they were sent, so
+	// compressed bodies must be decoded here, just as the
+	// default transport would do.
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		defer resp.Body.Close()
+		r, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = int64(len(body))
+		resp.Uncompressed = true
+	}
+	return resp, nil
+}
+
+// warcKey normalizes a URL for lookups.
+func warcKey(rawURL string) string {
+	u, err := url.Parse(strings.Trim(rawURL, "<>"))
+	if err != nil {
+		return rawURL
+	}
+	u.Scheme = ""
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	return strings.TrimSuffix(u.String(), "/")
+}
+
+// warcScan calls f with the header and starting offset of
+// every record in a WARC file.
+// For gzipped files, the offset is that of the gzip member
+// containing the record.
+func warcScan(path string, f func(header textproto.MIMEHeader, offset int64)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	counter := &countingReader{r: file}
+	r := bufio.NewReader(counter)
+	position := func() int64 {
+		return counter.n - int64(r.Buffered())
+	}
+
+	if filepath.Ext(path) != ".gz" {
+		for {
+			offset := position()
+			header, err := warcSkipRecord(r)
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			f(header, offset)
+		}
+	}
+
+	// Each gzip member normally holds one record, and
+	// reading the members one at a time lets us find their
+	// offsets.
+	var zr *gzip.Reader
+	for {
+		offset := position()
+		if zr == nil {
+			zr, err = gzip.NewReader(r)
+		} else {
+			err = zr.Reset(r)
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		zr.Multistream(false)
+		member := bufio.NewReader(zr)
+		for {
+			header, err := warcSkipRecord(member)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			f(header, offset)
+		}
+	}
+}
+
+// warcReadBlock reads the content block of the first
+// record at a location.
+func warcReadBlock(loc warcLocation) ([]byte, error) {
+	file, err := os.Open(loc.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(loc.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var r io.Reader = file
+	if filepath.Ext(loc.path) == ".gz" {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	br := bufio.NewReader(r)
+	header, err := warcReadHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid WARC Content-Length")
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(br, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// warcSkipRecord reads a record's header and skips past
+// its content block.
+func warcSkipRecord(r *bufio.Reader) (textproto.MIMEHeader, error) {
+	header, err := warcReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid WARC Content-Length")
+	}
+	if _, err := io.CopyN(ioutil.Discard, r, length); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// warcReadHeader reads the version line and named fields
+// of a record, skipping any blank lines left over from the
+// previous record.
+func warcReadHeader(r *bufio.Reader) (textproto.MIMEHeader, error) {
+	tp := textproto.NewReader(r)
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "WARC/") {
+			return nil, errors.New("invalid WARC version line: " + line)
+		}
+		break
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return header, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
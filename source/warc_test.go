@@ -0,0 +1,124 @@
+package source
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWARC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "warc_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := "http://www.newyorker.com/"
+	plain := []warcTestPage{
+		{"warcinfo", "", "", false},
+		{"response", root + "contributors/", "contributors.html", false},
+		{"request", root + "contributors/jane-doe/all/1", "", false},
+		{"response", root + "contributors/jane-doe/all/1", "jane-doe-1.html", true},
+	}
+	compressed := []warcTestPage{
+		{"response", root + "contributors/jane-doe/all/2", "jane-doe-2.html", false},
+		{"response", "https://www.newyorker.com/magazine/2016/11/21/a-long-piece",
+			"a-long-piece.html", true},
+	}
+	writeWARC(t, filepath.Join(dir, "crawl-1.warc"), plain, false)
+	writeWARC(t, filepath.Join(dir, "crawl-2.warc.gz"), compressed, true)
+
+	oldClient := HTTPClient
+	setEnv(t, map[string]string{
+		"WARC_FILES":  filepath.Join(dir, "*.warc") + "," + filepath.Join(dir, "*.warc.gz"),
+		"WARC_SOURCE": "NewYorker",
+	})
+
+	authors := collectAuthors(t, WARC{})
+	if len(authors) != 1 || authors[0].Name() != "Jane Doe" {
+		t.Fatal("unexpected authors:", authors)
+	}
+	arts := collectArticles(t, authors[0])
+	if len(arts) != 2 {
+		t.Fatal("expected 2 articles but got", len(arts))
+	}
+
+	body, err := arts[0].Body()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "The first paragraph of a long piece.\n\nThe second paragraph." {
+		t.Errorf("unexpected body: %q", body)
+	}
+	if date, err := arts[0].Date(); err != nil || date.Day() != 21 {
+		t.Errorf("unexpected date: %v (%v)", date, err)
+	}
+	names, err := arts[0].Authors()
+	if err != nil || len(names) != 2 || names[0] != "Jane Doe" || names[1] != "John Roe" {
+		t.Errorf("unexpected byline: %v (%v)", names, err)
+	}
+
+	_, err = arts[1].Body()
+	if httpErr, ok := err.(*HTTPError); !ok || httpErr.StatusCode != 404 {
+		t.Errorf("expected 404 for unarchived page but got: %v", err)
+	}
+
+	if HTTPClient != oldClient {
+		t.Error("HTTPClient was replaced")
+	}
+}
+
+type warcTestPage struct {
+	recordType string
+	url        string
+	fixture    string
+
+	// gzipped sends the fixture with Content-Encoding gzip.
+	gzipped bool
+}
+
+func writeWARC(t *testing.T, path string, pages []warcTestPage, compress bool) {
+	var out bytes.Buffer
+	for _, page := range pages {
+		var block []byte
+		if page.fixture != "" {
+			data, err := ioutil.ReadFile(filepath.Join("testdata", "newyorker", page.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			header := "HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n"
+			if page.gzipped {
+				var buf bytes.Buffer
+				w := gzip.NewWriter(&buf)
+				w.Write(data)
+				w.Close()
+				data = buf.Bytes()
+				header += "Content-Encoding: gzip\r\n"
+			}
+			block = []byte(fmt.Sprintf("%sContent-Length: %d\r\n\r\n%s", header, len(data),
+				data))
+		} else {
+			block = []byte("GET / HTTP/1.1\r\n\r\n")
+		}
+		record := fmt.Sprintf("WARC/1.0\r\nWARC-Type: %s\r\n", page.recordType)
+		if page.url != "" {
+			record += fmt.Sprintf("WARC-Target-URI: %s\r\n", page.url)
+		}
+		record += fmt.Sprintf("Content-Length: %d\r\n\r\n%s\r\n\r\n", len(block), block)
+
+		if compress {
+			w := gzip.NewWriter(&out)
+			w.Write([]byte(record))
+			w.Close()
+		} else {
+			out.WriteString(record)
+		}
+	}
+	if err := ioutil.WriteFile(path, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"math/rand"
+	"os"
 	"time"
 
 	"github.com/unixpickle/anynet/anyff"
@@ -13,6 +15,7 @@ import (
 	"github.com/unixpickle/essentials"
 	"github.com/unixpickle/rip"
 	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/textprint/clean"
 	"github.com/unixpickle/textprint/model"
 )
 
@@ -26,6 +29,8 @@ func main() {
 	var sampleDir string
 	var logInterval int
 	var maxLen int
+	var noQuotes bool
+	var quoteReport string
 
 	flag.StringVar(&netFile, "file", "out_net", "model output file")
 	flag.StringVar(&sampleDir, "samples", "", "sample directory")
@@ -34,6 +39,8 @@ func main() {
 	flag.IntVar(&maxLen, "maxlen", 0x200, "max article length")
 	flag.Float64Var(&stepSize, "step", 0.001, "step size")
 	flag.Float64Var(&validationFrac, "validation", 0.1, "validation fraction")
+	flag.BoolVar(&noQuotes, "noquotes", false, "remove quotes from samples")
+	flag.StringVar(&quoteReport, "quotereport", "", "file to list removed quotes in")
 
 	flag.Parse()
 
@@ -45,10 +52,18 @@ func main() {
 	m := readModel(netFile)
 
 	log.Println("Loading samples...")
-	samples, err := model.ReadSamples(sampleDir, maxLen)
+	var cleaners []model.Cleaner
+	var removed clean.Report
+	if noQuotes {
+		cleaners = append(cleaners, quoteCleaner(quoteReport, &removed))
+	}
+	samples, err := model.ReadSamples(sampleDir, maxLen, cleaners...)
 	if err != nil {
 		essentials.Die("Failed to read samples:", err)
 	}
+	if noQuotes {
+		log.Println("Quotes", removed)
+	}
 	validationData, trainingData := samples.Split(validationFrac)
 
 	defer func() {
@@ -83,6 +98,28 @@ func main() {
 	sgd.Run(rip.NewRIP().Chan())
 }
 
+// quoteCleaner creates a Cleaner which removes quotes,
+// adding up the text removed in total and optionally
+// writing a report for each article to a file.
+func quoteCleaner(reportPath string, total *clean.Report) model.Cleaner {
+	var reportFile *os.File
+	if reportPath != "" {
+		var err error
+		reportFile, err = os.Create(reportPath)
+		if err != nil {
+			essentials.Die("Failed to create quote report:", err)
+		}
+	}
+	return func(path, text string) string {
+		res, report := clean.Quotes(text)
+		total.Add(report)
+		if reportFile != nil {
+			fmt.Fprintf(reportFile, "%s\t%s\n", path, report)
+		}
+		return res
+	}
+}
+
 func readModel(path string) *model.Model {
 	var m *model.Model
 	if err := serializer.LoadAny(path, &m); err != nil {